@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"windspeed/helpers/strava"
+)
+
+// backfill stamps an athletes past activities. Without -after it resumes
+// the athletes last backfill from its checkpoint.
+func backfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	athleteId := fs.Int64("athlete", 0, "strava athlete id")
+	after := fs.String("after", "", "first day to backfill (YYYY-MM-DD)")
+	before := fs.String("before", time.Now().Format("2006-01-02"), "day after the last one to backfill (YYYY-MM-DD)")
+	fs.Parse(args)
+
+	if *athleteId == 0 {
+		log.Fatal("missing -athlete")
+	}
+	store := openStore()
+
+	if *after != "" {
+		start, err := time.Parse("2006-01-02", *after)
+		if err != nil {
+			log.Fatalf("invalid -after: %v", err)
+		}
+		end, err := time.Parse("2006-01-02", *before)
+		if err != nil {
+			log.Fatalf("invalid -before: %v", err)
+		}
+		if err := strava.StartBackfill(store, *athleteId, start, end); err != nil {
+			log.Fatalf("failed to start backfill: %v", err)
+		}
+	}
+
+	// Keep going until done, waiting out Stravas rate limits along the way.
+	for {
+		done, err := strava.RunBackfill(context.Background(), store, *athleteId, time.Now().Add(time.Hour))
+		var deferred *strava.DeferredError
+		if errors.As(err, &deferred) {
+			log.Printf("%v", deferred)
+			time.Sleep(time.Until(deferred.Until))
+			continue
+		}
+		if err != nil {
+			log.Fatalf("backfill failed: %v", err)
+		}
+		if done {
+			log.Printf("backfill done")
+			return
+		}
+	}
+}
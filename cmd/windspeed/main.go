@@ -0,0 +1,47 @@
+// Command windspeed runs maintenance tasks against the windspeed.app database,
+// or serves the app without Netlify.
+//
+// Usage:
+//
+//	windspeed backfill -athlete <id> [-after 2006-01-02] [-before 2006-01-02]
+//	windspeed restamp -athlete <id>
+//	windspeed rotate-keys [-new-key]
+//	windspeed migrate [-steps n] up|down|status
+//	windspeed serve [-addr :8080] [-public public] [-worker-interval 1m]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: windspeed <command> [flags]\n\ncommands:\n")
+	fmt.Fprintf(os.Stderr, "  backfill    stamp an athletes past activities\n")
+	fmt.Fprintf(os.Stderr, "  restamp     re-render an athletes stamps with their current settings\n")
+	fmt.Fprintf(os.Stderr, "  rotate-keys re-encrypt stored tokens with the current key\n")
+	fmt.Fprintf(os.Stderr, "  migrate     apply, roll back or list schema migrations\n")
+	fmt.Fprintf(os.Stderr, "  serve       run the site, webhook and worker as an http server\n")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "backfill":
+		backfill(os.Args[2:])
+	case "restamp":
+		restamp(os.Args[2:])
+	case "rotate-keys":
+		rotateKeys(os.Args[2:])
+	case "migrate":
+		migrate(os.Args[2:])
+	case "serve":
+		serve(os.Args[2:])
+	default:
+		usage()
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"windspeed/utils/database"
+	"windspeed/utils/migrations"
+)
+
+// migrate applies, rolls back or lists the schema migrations.
+func migrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	fs.Parse(args)
+
+	db := database.MustPool()
+
+	switch fs.Arg(0) {
+	case "up":
+		count, err := migrations.Up(db, database.Driver())
+		if err != nil {
+			log.Fatalf("migrate up failed after %v migrations: %v", count, err)
+		}
+		log.Printf("applied %v migrations", count)
+	case "down":
+		count, err := migrations.Down(db, database.Driver(), *steps)
+		if err != nil {
+			log.Fatalf("migrate down failed after %v migrations: %v", count, err)
+		}
+		log.Printf("rolled back %v migrations", count)
+	case "status":
+		statuses, err := migrations.Statuses(db, database.Driver())
+		if err != nil {
+			log.Fatalf("migrate status failed: %v", err)
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != 0 {
+				applied = time.Unix(s.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d  %-20s  %v\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: windspeed migrate [-steps n] up|down|status\n")
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"windspeed/helpers/strava"
+)
+
+// restamp rewrites an athletes stamps in their current units and format.
+func restamp(args []string) {
+	fs := flag.NewFlagSet("restamp", flag.ExitOnError)
+	athleteId := fs.Int64("athlete", 0, "strava athlete id")
+	fs.Parse(args)
+
+	if *athleteId == 0 {
+		log.Fatal("missing -athlete")
+	}
+	store := openStore()
+
+	for {
+		done, err := strava.RestampActivities(context.Background(), store, *athleteId, time.Now().Add(time.Hour))
+		var deferred *strava.DeferredError
+		if errors.As(err, &deferred) {
+			log.Printf("%v", deferred)
+			time.Sleep(time.Until(deferred.Until))
+			continue
+		}
+		if err != nil {
+			log.Fatalf("restamp failed: %v", err)
+		}
+		if done {
+			log.Printf("restamp done")
+			return
+		}
+	}
+}
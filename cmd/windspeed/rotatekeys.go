@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"windspeed/helpers/strava"
+	"windspeed/utils/secrets"
+)
+
+// rotateKeys re-encrypts stored tokens under the current key. Rotating is a
+// matter of adding a new key to TOKEN_KEYS, pointing TOKEN_KEY_ID at it and
+// running this command; the old key can be dropped once it has finished.
+func rotateKeys(args []string) {
+	fs := flag.NewFlagSet("rotate-keys", flag.ExitOnError)
+	newKey := fs.Bool("new-key", false, "print a new random key and exit")
+	fs.Parse(args)
+
+	if *newKey {
+		key, err := secrets.NewKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	rotated, err := strava.RotateTokenKeys(openStore())
+	if err != nil {
+		log.Fatalf("rotate-keys failed after %v users: %v", rotated, err)
+	}
+	log.Printf("re-encrypted tokens of %v users", rotated)
+}
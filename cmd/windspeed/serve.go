@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"windspeed/handlers"
+	"windspeed/utils/secrets"
+)
+
+// serve runs the site and the Strava handlers as a plain HTTP server, along
+// with the worker the Netlify schedule would otherwise run.
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	public := fs.String("public", "public", "directory of the static site, see scripts/build.go")
+	interval := fs.Duration("worker-interval", time.Minute, "how often to run queued jobs, 0 to disable")
+	fs.Parse(args)
+
+	// Refuse to start rather than store tokens unencrypted.
+	if _, err := secrets.FromEnv(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *interval > 0 {
+		go func() {
+			for range time.Tick(*interval) {
+				if err := handlers.Worker(context.Background()); err != nil {
+					log.Printf("worker failed: %v", err)
+				}
+			}
+		}()
+	}
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           handlers.NewMux(*public),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("listening on %v", *addr)
+	log.Fatal(server.ListenAndServe())
+}
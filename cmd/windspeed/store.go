@@ -0,0 +1,19 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"windspeed/helpers/strava"
+)
+
+// openStore opens the store, which every command but migrate needs, and
+// shares Strava's rate limit through it.
+func openStore() strava.Store {
+	store, err := strava.OpenStore(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	strava.ShareRateLimit(store)
+	return store
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	
+	"windspeed/helpers/strava"
+	"windspeed/utils/weather"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func authenticatedResponse(stravaResponse strava.Authorization) (*events.APIGatewayProxyResponse, error) {
+    session, cookie, err := newSession(stravaResponse.Athlete.ID, stravaResponse.Athlete.FirstName, false)
+    if err != nil {
+        return nil, err
+    }
+
+    // ask the user which units they prefer
+    settings := strava.Settings{
+        Units: weather.Imperial,
+        StampTemplate: weather.DefaultTemplate,
+        Destination: strava.DestinationDescription,
+    }
+    body, err := renderPage("authorized.html", settingsData(session, settings))
+    if err != nil {
+        return nil, err
+    }
+    return &events.APIGatewayProxyResponse{
+        StatusCode: 200,
+        Body: body,
+        Headers: map[string]string{
+            "Set-Cookie": cookie.String(),
+        },
+    }, nil
+}
+
+// AuthorizationSuccessful stores the tokens of a user who just connected
+// windspeed.app to Strava and asks them for their settings.
+func AuthorizationSuccessful(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+    if r.HTTPMethod == "GET" {
+        code := r.QueryStringParameters["code"]
+        if code == "" {
+            return &events.APIGatewayProxyResponse{
+                StatusCode: 500,
+                Body: "authorization code not found in strava response",
+            }, nil
+        } else {
+            // opened first, so the exchange counts against the shared rate limit
+            store, err := openStore(context.Background())
+            if err != nil {
+                return storeUnavailable(err), nil
+            }
+
+            // get tokens from Strava
+            stravaResponse, err := strava.API.ExchangeCode(context.Background(), code)
+            if err != nil {
+                log.Printf("error getting user tokens from strava: %v\n", err)
+                return &events.APIGatewayProxyResponse{
+                    StatusCode: 500,
+                    Body: "failed to get tokens from strava",
+                }, nil
+            }
+            
+            // persist new user credentials
+            strava.AddNewUser(store, stravaResponse.Athlete.ID, stravaResponse.AccessToken, stravaResponse.RefreshToken, stravaResponse.ExpiresAt)
+        
+            return authenticatedResponse(stravaResponse)
+        }
+    }
+    return nil, nil
+}
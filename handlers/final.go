@@ -0,0 +1,185 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "html/template"
+    "log"
+    "net/url"
+    "strconv"
+	"strings"
+	"time"
+
+    "windspeed/helpers/strava"
+    "windspeed/templates"
+    "windspeed/utils/weather"
+    
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaxBackfillDays is the furthest back a user can ask to backfill from here.
+const MaxBackfillDays int = 365
+
+// Final saves the settings picked on the authorization or settings page.
+func Final(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+    // Parse out user settings, the submit action and CSRF token.
+    formValues, _ := url.ParseQuery(r.Body)
+    units, unitsErr := weather.ParseUnits(formValues.Get("temperature_unit"), formValues.Get("wind_unit"), formValues.Get("pressure_unit"))
+    stampTemplate := strings.ReplaceAll(formValues.Get("stamp_template"), "\r\n", "\n")
+    action := formValues.Get("action")
+    backfillDays, _ := strconv.Atoi(formValues.Get("backfill_days"))
+    restamp := formValues.Get("restamp") == "yes"
+    activityTypes := formValues["activity_types"]
+    destination := formValues.Get("destination")
+    csrfForm := formValues.Get("csrf_token")
+
+    // Get cookie and decode.
+    cookie := readSession(r)
+    cookieIsValid := validSession(cookie, csrfForm)
+
+    // Persist new user settings.
+    athleteId, _ := strconv.ParseInt(cookie["id"],  10, 64)
+    if cookieIsValid {
+        // Show the settings form again to preview the stamp, or to fix an invalid template.
+        settings := strava.Settings{Units: units, StampTemplate: stampTemplate, ActivityTypes: activityTypes, Destination: destination}
+        preview, previewErr := weather.PreviewStamp(stampTemplate, units)
+        if destination == strava.DestinationName {
+            preview = weather.PreviewShortStamp(units)
+        }
+        if !strava.IsDestination(destination) {
+            previewErr = fmt.Errorf("unsupported destination: %q", destination)
+        }
+        if unitsErr != nil {
+            previewErr = unitsErr
+        }
+        if activityTypesErr := validateActivityTypes(activityTypes); activityTypesErr != nil {
+            previewErr = activityTypesErr
+        }
+        if action == "Preview" || previewErr != nil {
+            return settingsResponse(cookie, settings, backfillDays, restamp, preview, previewErr)
+        }
+        store, err := openStore(context.Background())
+        if err != nil {
+            return storeUnavailable(err), nil
+        }
+        strava.AddUserSettings(store, athleteId, settings)
+
+        // Queue up a backfill of past activities, if the user opted in.
+        if backfillDays > 0 && backfillDays <= MaxBackfillDays {
+            now := time.Now()
+            err := strava.StartBackfill(store, athleteId, now.AddDate(0, 0, -backfillDays), now)
+            if err == nil {
+                err = strava.EnqueueJob(store, strava.JobBackfill, athleteId, 0)
+            }
+            if err != nil {
+                log.Printf("> failed to start backfill: %v\n", err)
+                backfillDays = 0
+            }
+        } else {
+            backfillDays = 0
+        }
+
+        // Rewrite existing stamps with the new settings, if asked to.
+        if restamp {
+            if err := strava.EnqueueJob(store, strava.JobRestamp, athleteId, 0); err != nil {
+                log.Printf("> failed to queue re-rendering stamps: %v\n", err)
+            }
+        }
+
+        // Prepare HTML templates for rendering.
+		data := map[string]interface{}{
+			"firstName": cookie["fn"],
+			"manage": cookie["manage"],
+		}
+		if backfillDays > 0 {
+			data["backfillDays"] = strconv.Itoa(backfillDays)
+		}
+		body, err := renderPage("final.html", data)
+		if err != nil {
+			return nil, err
+		}
+
+        // Send users to final confirmation page, ending their session.
+        return &events.APIGatewayProxyResponse{
+            StatusCode: 200,
+            Body: body,
+            Headers: map[string]string{
+                "Set-Cookie": endSession().String(),
+            },
+        }, nil
+    } else {
+        return &events.APIGatewayProxyResponse{
+            StatusCode: 200,
+            Body: "expired session",
+        }, nil
+    }
+}
+
+func validateActivityTypes(activityTypes []string) error {
+    if len(activityTypes) == 0 {
+        return errors.New("pick at least one activity type")
+    }
+    for _, t := range activityTypes {
+        if !strava.IsActivityType(t) {
+            return fmt.Errorf("unsupported activity type: %q", t)
+        }
+    }
+    return nil
+}
+
+// settingsData fills in the settings form, see settings_form.html.
+func settingsData(session map[string]string, settings strava.Settings) map[string]interface{} {
+    return map[string]interface{}{
+        "csrfToken": session["csrf"],
+        "firstName": session["fn"],
+        "temperatureUnit": settings.Units.Temperature,
+        "windUnit": settings.Units.Wind,
+        "pressureUnit": settings.Units.Pressure,
+        "stampTemplate": settings.StampTemplate,
+        "activityTypes": strava.ActivityTypeOptions(settings.ActivityTypes),
+        "destination": settings.Destination,
+        "fields": strings.Join(weather.TemplateFields, ", "),
+        "maxLength": fmt.Sprintf("%d", weather.MaxTemplateLength),
+    }
+}
+
+// renderPage executes one of the embedded templates.
+func renderPage(name string, data interface{}) (string, error) {
+    tmpl := template.Must(template.ParseFS(templates.FS, "*.html"))
+    buf := new(bytes.Buffer)
+    if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
+        return "", err
+    }
+    return buf.String(), nil
+}
+
+// settingsResponse shows the settings form again, on the page the user
+// started from.
+func settingsResponse(cookie map[string]string, settings strava.Settings, backfillDays int, restamp bool, preview string, previewErr error) (*events.APIGatewayProxyResponse, error) {
+    data := settingsData(cookie, settings)
+    data["preview"] = preview
+    if backfillDays > 0 {
+        data["backfillDays"] = strconv.Itoa(backfillDays)
+    }
+    if restamp {
+        data["restamp"] = "yes"
+    }
+    if previewErr != nil {
+        data["previewError"] = previewErr.Error()
+    }
+
+    page := "authorized.html"
+    if cookie["manage"] == "yes" {
+        page = "settings.html"
+    }
+    body, err := renderPage(page, data)
+    if err != nil {
+        return nil, err
+    }
+    return &events.APIGatewayProxyResponse{
+        StatusCode: 200,
+        Body: body,
+    }, nil
+}
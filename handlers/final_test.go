@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"windspeed/helpers/strava"
+	"windspeed/utils/weather"
+)
+
+func settingsForm(action string) url.Values {
+	return url.Values{
+		"temperature_unit": {weather.Celsius},
+		"wind_unit":        {weather.Beaufort},
+		"pressure_unit":    {weather.Hectopascal},
+		"stamp_template":   {"{{.temp}}{{.temp_unit}}\r\nwind: {{.wind}} {{.wind_unit}}"},
+		"activity_types":   {"Ride", "Run"},
+		"destination":      {strava.DestinationPrivateNote},
+		"action":           {action},
+	}
+}
+
+func TestFinalPreview(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(url.Values)
+		error string
+	}{
+		{"preview", func(url.Values) {}, ""},
+		{"invalid template", func(form url.Values) { form.Set("stamp_template", "{{range .temp}}x{{end}}") }, "stamp-error"},
+		{"unknown field", func(form url.Values) { form.Set("stamp_template", "{{.altitude}}") }, "stamp-error"},
+		{"unknown unit", func(form url.Values) { form.Set("wind_unit", "ft/s") }, "unsupported wind speed unit"},
+		{"no activity types", func(form url.Values) { form.Del("activity_types") }, "pick at least one activity type"},
+		{"unknown destination", func(form url.Values) { form.Set("destination", "title") }, "unsupported destination"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, _ := setup(t)
+			form := settingsForm("Preview")
+			test.edit(form)
+			if test.error != "" {
+				form.Set("action", "Save")
+			}
+
+			resp, err := Final(formRequest(t, 7, true, form))
+			if err != nil || resp.StatusCode != 200 {
+				t.Fatalf("Final = %+v, %v, want 200", resp, err)
+			}
+			if test.error == "" {
+				units := weather.Units{Temperature: weather.Celsius, Wind: weather.Beaufort, Pressure: weather.Hectopascal}
+				preview, _ := weather.PreviewStamp("{{.temp}}{{.temp_unit}}\nwind: {{.wind}} {{.wind_unit}}", units)
+				if !strings.Contains(resp.Body, template.HTMLEscapeString(preview)) || strings.Contains(resp.Body, "stamp-error") {
+					t.Errorf("preview %q not shown in %q", preview, resp.Body)
+				}
+			} else if !strings.Contains(resp.Body, test.error) {
+				t.Errorf("error %q not shown in %q", test.error, resp.Body)
+			}
+			if _, ok := resp.Headers["Set-Cookie"]; ok {
+				t.Errorf("session ended on preview")
+			}
+			if _, err := store.GetSettings(context.Background(), 7); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("settings were saved on preview: %v", err)
+			}
+		})
+	}
+}
+
+func TestFinalSave(t *testing.T) {
+	tests := []struct {
+		name         string
+		backfillDays string
+		restamp      string
+		jobs         map[string]string
+		page         string
+	}{
+		{"settings only", "", "", map[string]string{}, "The service is now fully configured"},
+		{"with backfill", "30", "", map[string]string{"backfill 7/0": strava.JobPending}, "past 30 days"},
+		{"backfill too long", "3650", "", map[string]string{}, "The service is now fully configured"},
+		{"with restamp", "", "yes", map[string]string{"restamp 7/0": strava.JobPending}, "The service is now fully configured"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, _ := setup(t)
+			form := settingsForm("Save")
+			form.Set("backfill_days", test.backfillDays)
+			form.Set("restamp", test.restamp)
+
+			resp, err := Final(formRequest(t, 7, false, form))
+			if err != nil || resp.StatusCode != 200 {
+				t.Fatalf("Final = %+v, %v, want 200", resp, err)
+			}
+			if !strings.Contains(resp.Body, test.page) {
+				t.Errorf("page does not say %q: %q", test.page, resp.Body)
+			}
+			if !strings.Contains(resp.Headers["Set-Cookie"], "windspeed=") {
+				t.Errorf("session was not ended: %v", resp.Headers)
+			}
+
+			settings, err := store.GetSettings(context.Background(), 7)
+			want := strava.Settings{
+				Units:         weather.Units{Temperature: weather.Celsius, Wind: weather.Beaufort, Pressure: weather.Hectopascal},
+				StampTemplate: "{{.temp}}{{.temp_unit}}\nwind: {{.wind}} {{.wind_unit}}",
+				ActivityTypes: []string{"Ride", "Run"},
+				Destination:   strava.DestinationPrivateNote,
+			}
+			if err != nil || !reflect.DeepEqual(settings, want) {
+				t.Errorf("saved settings = %+v, %v, want %+v", settings, err, want)
+			}
+			if jobs := jobsOf(store); !reflect.DeepEqual(jobs, test.jobs) {
+				t.Errorf("jobs = %v, want %v", jobs, test.jobs)
+			}
+		})
+	}
+}
+
+func TestFinalExpiredSession(t *testing.T) {
+	store, _ := setup(t)
+	form := settingsForm("Save")
+	form.Set("csrf_token", "forged")
+
+	resp, err := Final(formRequest(t, 7, false, form))
+	if err != nil || resp.Body != "expired session" {
+		t.Fatalf("Final = %+v, %v, want an expired session", resp, err)
+	}
+	if _, err := store.GetSettings(context.Background(), 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("settings were saved: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gorilla/securecookie"
+)
+
+// fakeStrava serves the parts of the Strava api used by the handlers and the
+// jobs they queue, keeping the activity descriptions by id.
+type fakeStrava struct {
+	mu           sync.Mutex
+	requests     []string
+	descriptions map[int64]string
+}
+
+func (s *fakeStrava) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	var id int64
+	switch {
+	case r.URL.Path == "/oauth/deauthorize":
+		fmt.Fprint(w, `{}`)
+	case r.URL.Path == "/api/v3/push_subscriptions":
+		fmt.Fprint(w, `[]`)
+	case r.Method == "GET" && scan(r.URL.Path, "/api/v3/activities/%d", &id):
+		description, ok := s.descriptions[id]
+		if !ok {
+			http.Error(w, `{"message":"Record Not Found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "description": description})
+	case r.Method == "PUT" && scan(r.URL.Path, "/api/v3/activities/%d", &id):
+		var fields map[string]string
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &fields)
+		if description, ok := fields["description"]; ok {
+			s.descriptions[id] = description
+		}
+		fmt.Fprint(w, `{}`)
+	default:
+		http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+	}
+}
+
+func scan(path string, format string, id *int64) bool {
+	n, err := fmt.Sscanf(path, format, id)
+	return err == nil && n == 1
+}
+
+func (s *fakeStrava) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *fakeStrava) Description(id int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.descriptions[id]
+}
+
+// setup points the handlers at a fresh MemoryStore and a fake Strava api.
+func setup(t *testing.T) (*strava.MemoryStore, *fakeStrava) {
+	t.Helper()
+	t.Setenv("HASH_KEY", strings.Repeat("h", 32))
+	t.Setenv("BLOCK_KEY", strings.Repeat("b", 32))
+	t.Setenv("TOKEN_KEYS", "test:"+base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+
+	store := strava.NewMemoryStore()
+	api := &fakeStrava{descriptions: map[int64]string{}}
+	srv := httptest.NewServer(api)
+
+	openStore, client := strava.OpenStore, strava.API
+	strava.OpenStore = func(ctx context.Context) (strava.Store, error) {
+		return store, nil
+	}
+	strava.API = strava.NewClient(srv.Client(), srv.URL, "client-id", "client-secret")
+	t.Cleanup(func() {
+		srv.Close()
+		strava.OpenStore, strava.API = openStore, client
+	})
+	return store, api
+}
+
+// formRequest posts the form with a session of the athlete, as if sent from
+// the settings page. The session is forged with the keys set by setup.
+func formRequest(t *testing.T, athleteId int64, manage bool, form url.Values) events.APIGatewayProxyRequest {
+	t.Helper()
+	session := map[string]string{
+		"id":   fmt.Sprintf("%v", athleteId),
+		"exp":  fmt.Sprintf("%v", time.Now().Add(time.Minute).Unix()),
+		"fn":   "Jane",
+		"csrf": "token",
+	}
+	if manage {
+		session["manage"] = "yes"
+	}
+	codec := securecookie.New([]byte(os.Getenv("HASH_KEY")), []byte(os.Getenv("BLOCK_KEY")))
+	cookie, err := codec.Encode("windspeed", session)
+	if err != nil {
+		t.Fatalf("failed to encode session: %v", err)
+	}
+	if form.Get("csrf_token") == "" {
+		form.Set("csrf_token", session["csrf"])
+	}
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: "POST",
+		Headers:    map[string]string{"Cookie": "windspeed=" + cookie},
+		Body:       form.Encode(),
+	}
+}
+
+// jobsOf returns the status of each job by kind and object id.
+func jobsOf(store *strava.MemoryStore) map[string]string {
+	jobs := map[string]string{}
+	for job, status := range store.Jobs() {
+		jobs[fmt.Sprintf("%v %v/%v", job.Kind, job.AthleteId, job.ObjectId)] = status
+	}
+	return jobs
+}
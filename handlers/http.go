@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// MaxBodySize caps the request bodies read by Handler.
+const MaxBodySize int64 = 1 << 20
+
+// ProxyHandler is the signature the Lambda functions are started with.
+type ProxyHandler func(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error)
+
+// Handler serves a ProxyHandler over plain net/http, translating requests
+// and responses the way API Gateway does.
+func Handler(h ProxyHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := proxyRequest(r)
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		resp, err := h(req)
+		if err != nil {
+			log.Printf("> %v %v failed: %v\n", r.Method, r.URL.Path, err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if resp == nil {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeProxyResponse(w, resp)
+	})
+}
+
+func proxyRequest(r *http.Request) (events.APIGatewayProxyRequest, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodySize))
+	if err != nil {
+		return events.APIGatewayProxyRequest{}, err
+	}
+
+	headers := map[string]string{}
+	for name, values := range r.Header {
+		separator := ","
+		if name == "Cookie" {
+			separator = "; "
+		}
+		headers[name] = strings.Join(values, separator)
+	}
+	query := map[string]string{}
+	for name, values := range r.URL.Query() {
+		query[name] = values[len(values)-1]
+	}
+
+	return events.APIGatewayProxyRequest{
+		Resource:                        r.URL.Path,
+		Path:                            r.URL.Path,
+		HTTPMethod:                      r.Method,
+		Headers:                         headers,
+		MultiValueHeaders:               r.Header,
+		QueryStringParameters:           query,
+		MultiValueQueryStringParameters: r.URL.Query(),
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Path:       r.URL.Path,
+			HTTPMethod: r.Method,
+			Identity:   events.APIGatewayRequestIdentity{SourceIP: r.RemoteAddr, UserAgent: r.UserAgent()},
+		},
+		Body: string(body),
+	}, nil
+}
+
+func writeProxyResponse(w http.ResponseWriter, resp *events.APIGatewayProxyResponse) {
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	for name, values := range resp.MultiValueHeaders {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		decoded, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			log.Printf("> invalid base64 response body: %v\n", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		body = decoded
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
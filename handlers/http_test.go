@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestHandlerRequest(t *testing.T) {
+	var got events.APIGatewayProxyRequest
+	handler := Handler(func(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		got = r
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	r := httptest.NewRequest("POST", "/settings?unit=kmh&tz=UTC&tz=Europe/Oslo", strings.NewReader("stamp=on"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Add("Cookie", "session=a")
+	r.Header.Add("Cookie", "other=b")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got.HTTPMethod != "POST" || got.Path != "/settings" || got.Body != "stamp=on" {
+		t.Errorf("request = %v %v %q, want POST /settings %q", got.HTTPMethod, got.Path, got.Body, "stamp=on")
+	}
+	if want := map[string]string{"unit": "kmh", "tz": "Europe/Oslo"}; !reflect.DeepEqual(got.QueryStringParameters, want) {
+		t.Errorf("query = %v, want %v", got.QueryStringParameters, want)
+	}
+	if want := []string{"UTC", "Europe/Oslo"}; !reflect.DeepEqual(got.MultiValueQueryStringParameters["tz"], want) {
+		t.Errorf("multi-value query = %v, want %v", got.MultiValueQueryStringParameters["tz"], want)
+	}
+	if cookie := got.Headers["Cookie"]; cookie != "session=a; other=b" {
+		t.Errorf("Cookie header = %q, want the cookies joined with \"; \"", cookie)
+	}
+	if contentType := got.Headers["Content-Type"]; contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Content-Type header = %q", contentType)
+	}
+}
+
+func TestHandlerRequestTooLarge(t *testing.T) {
+	called := false
+	handler := Handler(func(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+		called = true
+		return &events.APIGatewayProxyResponse{StatusCode: http.StatusOK}, nil
+	})
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(strings.Repeat("x", int(MaxBodySize)+1))
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/webhook", body))
+	if w.Code != http.StatusBadRequest || called {
+		t.Errorf("status = %v, called = %v, want 400 without calling the handler", w.Code, called)
+	}
+}
+
+func TestHandlerResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *events.APIGatewayProxyResponse
+		err     error
+		status  int
+		body    string
+		headers http.Header
+	}{
+		{"headers", &events.APIGatewayProxyResponse{
+			StatusCode:        http.StatusFound,
+			Headers:           map[string]string{"Location": "/settings"},
+			MultiValueHeaders: map[string][]string{"Set-Cookie": {"a=1", "b=2"}},
+		}, nil, http.StatusFound, "", http.Header{"Location": {"/settings"}, "Set-Cookie": {"a=1", "b=2"}}},
+		{"default status", &events.APIGatewayProxyResponse{Body: "ok"}, nil, http.StatusOK, "ok", nil},
+		{"base64", &events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "aGVsbG8=", IsBase64Encoded: true},
+			nil, http.StatusOK, "hello", nil},
+		{"invalid base64", &events.APIGatewayProxyResponse{StatusCode: http.StatusOK, Body: "!!", IsBase64Encoded: true},
+			nil, http.StatusInternalServerError, "internal server error\n", nil},
+		{"error", nil, errors.New("failed"), http.StatusInternalServerError, "internal server error\n", nil},
+		{"no response", nil, nil, http.StatusMethodNotAllowed, "method not allowed\n", nil},
+	}
+	for _, test := range tests {
+		handler := Handler(func(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+			return test.resp, test.err
+		})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+		body, _ := io.ReadAll(w.Result().Body)
+		if w.Code != test.status || string(body) != test.body {
+			t.Errorf("%v: response = %v %q, want %v %q", test.name, w.Code, body, test.status, test.body)
+		}
+		for name, values := range test.headers {
+			if got := w.Result().Header.Values(name); !reflect.DeepEqual(got, values) {
+				t.Errorf("%v: %v header = %v, want %v", test.name, name, got, values)
+			}
+		}
+	}
+}
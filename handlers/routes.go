@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// Routes are the paths the Netlify redirects in netlify.toml point at the
+// functions.
+var Routes = map[string]ProxyHandler{
+	"/api/strava/webhook":                           Webhook,
+	"/integrations/strava/authorization-successful": AuthorizationSuccessful,
+	"/integrations/strava/final":                    Final,
+	"/integrations/strava/final/":                   Final,
+	"/integrations/strava/settings":                 ManageSettings,
+	"/integrations/strava/unsubscribe":              Unsubscribe,
+}
+
+// NewMux mounts the handlers on their routes, and serves the static site
+// from publicDir for everything else, like Netlify does.
+func NewMux(publicDir string) *http.ServeMux {
+	mux := http.NewServeMux()
+	for path, h := range Routes {
+		mux.Handle(path, Handler(h))
+	}
+	mux.Handle("/", http.FileServer(http.Dir(publicDir)))
+	return mux
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/gorilla/securecookie"
+)
+
+// SessionCookie holds the signed session of a user editing their settings.
+const SessionCookie string = "windspeed"
+
+// SignupSessionDuration is how long new users have to pick their settings.
+const SignupSessionDuration time.Duration = 5 * time.Minute
+
+// ManageSessionDuration is how long existing users who signed in again can
+// change their settings.
+const ManageSessionDuration time.Duration = 30 * time.Minute
+
+func sessionCodec() *securecookie.SecureCookie {
+	return securecookie.New([]byte(os.Getenv("HASH_KEY")), []byte(os.Getenv("BLOCK_KEY")))
+}
+
+// newSession starts a session for the athlete, with a fresh CSRF token. If
+// manage is set, the user is an existing subscriber changing their settings.
+func newSession(athleteId int64, firstName string, manage bool) (map[string]string, *http.Cookie, error) {
+	csrf := make([]byte, 64)
+	if _, err := rand.Read(csrf); err != nil {
+		return nil, nil, err
+	}
+
+	duration := SignupSessionDuration
+	if manage {
+		duration = ManageSessionDuration
+	}
+	session := map[string]string{
+		"id":   fmt.Sprintf("%v", athleteId),
+		"exp":  fmt.Sprintf("%v", time.Now().Add(duration).Unix()),
+		"fn":   firstName,
+		"csrf": hex.EncodeToString(csrf),
+	}
+	if manage {
+		session["manage"] = "yes"
+	}
+	cookie, err := sessionCookie(session)
+	return session, cookie, err
+}
+
+// endSession returns a cookie replacing the session with an expired one.
+func endSession() *http.Cookie {
+	cookie, _ := sessionCookie(map[string]string{"exp": "0", "csrf": ""})
+	return cookie
+}
+
+func sessionCookie(session map[string]string) (*http.Cookie, error) {
+	encoded, err := sessionCodec().Encode(SessionCookie, session)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Cookie{
+		Name:     SessionCookie,
+		Value:    encoded,
+		SameSite: http.SameSiteStrictMode,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+	}, nil
+}
+
+// readSession decodes the session cookie of the request. It returns an
+// empty session if there is none or it can't be decoded.
+func readSession(r events.APIGatewayProxyRequest) map[string]string {
+	session := make(map[string]string)
+	for headerName, headerValue := range r.Headers {
+		if strings.ToLower(headerName) != "cookie" {
+			continue
+		}
+		for _, part := range strings.Split(headerValue, ";") {
+			cookieParts := strings.SplitN(strings.TrimSpace(part), "=", 2)
+			if len(cookieParts) == 2 && cookieParts[0] == SessionCookie {
+				if err := sessionCodec().Decode(SessionCookie, cookieParts[1], &session); err != nil {
+					log.Printf("> failed to decode secure cookie: %v\n", err)
+				}
+			}
+		}
+	}
+	return session
+}
+
+// validSession reports whether the session has not expired and was started
+// along with the form that sent csrfToken.
+func validSession(session map[string]string, csrfToken string) bool {
+	exp, _ := strconv.ParseInt(session["exp"], 10, 64)
+	return session["csrf"] != "" && session["csrf"] == csrfToken && exp > time.Now().Unix()
+}
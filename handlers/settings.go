@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ManageSettings signs in a subscriber with Strava again and shows them
+// their current settings, see settings.html. Athletes who are not
+// subscribed yet are signed up instead.
+func ManageSettings(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	if r.HTTPMethod != "GET" {
+		return nil, nil
+	}
+	code := r.QueryStringParameters["code"]
+	if code == "" {
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "authorization code not found in strava response",
+		}, nil
+	}
+
+	ctx := context.Background()
+	stravaResponse, err := strava.API.ExchangeCode(ctx, code)
+	if err != nil {
+		log.Printf("error getting user tokens from strava: %v\n", err)
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "failed to get tokens from strava",
+		}, nil
+	}
+	store, err := openStore(ctx)
+	if err != nil {
+		return storeUnavailable(err), nil
+	}
+
+	athlete := stravaResponse.Athlete
+	subscribed, err := strava.SignInUser(store, athlete.ID, stravaResponse.AccessToken, stravaResponse.RefreshToken, stravaResponse.ExpiresAt)
+	if err != nil {
+		log.Printf("> failed to sign in strava user %v: %v\n", athlete.ID, err)
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "failed to sign in",
+		}, nil
+	}
+	if !subscribed {
+		strava.AddNewUser(store, athlete.ID, stravaResponse.AccessToken, stravaResponse.RefreshToken, stravaResponse.ExpiresAt)
+		return authenticatedResponse(stravaResponse)
+	}
+
+	settings, err := strava.GetUserSettings(store, athlete.ID)
+	if err != nil {
+		log.Printf("> failed to read settings of strava user %v: %v\n", athlete.ID, err)
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "failed to read settings",
+		}, nil
+	}
+	session, cookie, err := newSession(athlete.ID, athlete.FirstName, true)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderPage("settings.html", settingsData(session, settings))
+	if err != nil {
+		return nil, err
+	}
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       body,
+		Headers: map[string]string{
+			"Set-Cookie": cookie.String(),
+		},
+	}, nil
+}
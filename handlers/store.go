@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// openStore opens the store and shares Strava's rate limit through it, see
+// strava.ShareRateLimit.
+func openStore(ctx context.Context) (strava.Store, error) {
+	store, err := strava.OpenStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	strava.ShareRateLimit(store)
+	return store, nil
+}
+
+// storeUnavailable logs why openStore failed and answers with a 500.
+func storeUnavailable(err error) *events.APIGatewayProxyResponse {
+	log.Printf("> %v\n", err)
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 500,
+		Body:       "database unavailable",
+	}
+}
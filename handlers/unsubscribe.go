@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+
+	"windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Unsubscribe queues the removal of a signed in subscriber at their
+// request, see settings.html and strava.RemoveUser.
+func Unsubscribe(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	if r.HTTPMethod != "POST" {
+		return nil, nil
+	}
+	formValues, _ := url.ParseQuery(r.Body)
+	session := readSession(r)
+	if !validSession(session, formValues.Get("csrf_token")) || session["manage"] != "yes" {
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Body:       "expired session",
+		}, nil
+	}
+	athleteId, _ := strconv.ParseInt(session["id"], 10, 64)
+
+	store, err := openStore(context.Background())
+	if err != nil {
+		return storeUnavailable(err), nil
+	}
+	if err := strava.UnsubscribeUser(store, athleteId); err != nil {
+		log.Printf("> %v\n", err)
+		return &events.APIGatewayProxyResponse{
+			StatusCode: 500,
+			Body:       "failed to unsubscribe, please try again later",
+		}, nil
+	}
+
+	body, err := renderPage("unsubscribed.html", map[string]interface{}{"firstName": session["fn"]})
+	if err != nil {
+		return nil, err
+	}
+	return &events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Body:       body,
+		Headers: map[string]string{
+			"Set-Cookie": endSession().String(),
+		},
+	}, nil
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"windspeed/helpers/strava"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	store, api := setup(t)
+	ctx := context.Background()
+	stamp := "20.1°C, clouds: 0%, humidity: 40%, wind: 12.6 km/h ←"
+	strava.AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+	strava.AddUserSettings(store, 7, strava.Settings{StampTemplate: "{{.temp}}"})
+	if err := strava.EnqueueJob(store, strava.JobBackfill, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+	api.descriptions[5] = "Morning ride\n" + stamp
+	if err := store.SaveStamp(ctx, strava.Stamp{ActivityId: 5, AthleteId: 7, Destination: strava.DestinationDescription, Text: stamp}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := Unsubscribe(formRequest(t, 7, true, url.Values{}))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("Unsubscribe = %+v, %v, want 200", resp, err)
+	}
+	if !strings.Contains(resp.Body, "You have been unsubscribed") {
+		t.Errorf("unexpected page: %q", resp.Body)
+	}
+	if !strings.Contains(resp.Headers["Set-Cookie"], SessionCookie+"=") {
+		t.Errorf("session was not ended: %v", resp.Headers)
+	}
+
+	// The request only queues the removal.
+	if requests := api.Requests(); len(requests) != 0 {
+		t.Errorf("Unsubscribe called Strava: %v", requests)
+	}
+	want := map[string]string{"backfill 7/0": strava.JobPending, "unsubscribe 7/0": strava.JobPending}
+	if jobs := jobsOf(store); !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+
+	strava.ProcessJobs(context.Background(), store, 10)
+	if got := api.Description(5); got != "Morning ride" {
+		t.Errorf("stamp was not removed: %q", got)
+	}
+	if requests := api.Requests(); len(requests) == 0 || requests[len(requests)-1] != "POST /oauth/deauthorize" {
+		t.Errorf("user was not deauthorized: %v", requests)
+	}
+	if _, err := store.GetSubscriber(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscriber was not deleted: %v", err)
+	}
+	if _, err := store.GetSettings(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("settings were not deleted: %v", err)
+	}
+	if jobs := jobsOf(store); len(jobs) != 0 {
+		t.Errorf("jobs were not deleted: %v", jobs)
+	}
+}
+
+func TestUnsubscribeRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		manage bool
+		form   url.Values
+	}{
+		{"signup session", false, url.Values{}},
+		{"forged form", true, url.Values{"csrf_token": {"forged"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, api := setup(t)
+			strava.AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+
+			resp, err := Unsubscribe(formRequest(t, 7, test.manage, test.form))
+			if err != nil || resp.Body != "expired session" {
+				t.Fatalf("Unsubscribe = %+v, %v, want an expired session", resp, err)
+			}
+			if jobs := jobsOf(store); len(jobs) != 0 {
+				t.Errorf("jobs were queued: %v", jobs)
+			}
+			if requests := api.Requests(); len(requests) != 0 {
+				t.Errorf("Unsubscribe called Strava: %v", requests)
+			}
+		})
+	}
+}
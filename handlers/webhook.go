@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+    "windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type StravaPost struct {
+	AspectType       string    `json:"aspect_type"`
+	EventTime        int64     `json:"event_time"`
+	ObjectId         int64     `json:"object_id"`
+	ObjectType       string    `json:"object_type"`
+	OwnerId          int64     `json:"owner_id"`
+	SubscriptionId   int64     `json:"subscription_id"`
+	Updates map[string]interface{} `json:"updates"`
+}
+
+// Webhook receives Strava's webhook events and answers its subscription
+// challenge.
+func Webhook(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+	switch r.HTTPMethod {
+	case "POST":
+		return process_webhook_post(r)
+	case "GET":
+		return process_webhook_get(r)
+    }
+    return nil, nil
+}
+
+func process_webhook_post(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+    var stravaPost StravaPost
+    err := json.Unmarshal([]byte(r.Body), &stravaPost)
+    if err != nil {
+        log.Printf("> json error: %v\n", err)
+    }
+
+    // Record the event before any api calls, skipping deliveries seen before.
+    // Strava only says which of the title, type and privacy changed, so every
+    // update is checked again, e.g. for a GPS track added later. Activities
+    // that are already stamped are skipped quickly.
+    // A deauthorization is cleaned up by a queued job as well, as it may take
+    // longer than Strava waits for an answer.
+    var jobKind string
+    if stravaPost.ObjectType == "activity" && (stravaPost.AspectType == "create" || stravaPost.AspectType == "update") {
+        jobKind = strava.JobAddWeather
+    } else if stravaPost.ObjectType == "athlete" && fmt.Sprint(stravaPost.Updates["authorized"]) == "false" {
+        jobKind = strava.JobUnsubscribe
+    }
+    event := strava.WebhookEvent{
+        OwnerId: stravaPost.OwnerId,
+        ObjectId: stravaPost.ObjectId,
+        AspectType: stravaPost.AspectType,
+        EventTime: stravaPost.EventTime,
+    }
+    store, err := openStore(context.Background())
+    if err != nil {
+        return storeUnavailable(err), nil
+    }
+    isNew, err := strava.RecordWebhookEvent(store, event, jobKind)
+    if err != nil {
+        log.Printf("> failed to record webhook event for object %v: %v\n", stravaPost.ObjectId, err)
+        return &events.APIGatewayProxyResponse{
+            StatusCode: 500,
+            Body: "failed to store webhook event",
+        }, nil
+    }
+
+    if !isNew {
+        log.Printf("> webhook event already processed\n")
+    } else if jobKind != "" {
+        // Left to the scheduled worker, so Strava gets its answer in time.
+        log.Printf("> %v job queued for object %v\n", jobKind, stravaPost.ObjectId)
+    } else if stravaPost.ObjectType == "activity" && stravaPost.AspectType == "delete" {
+        defer strava.DeleteActivity(store, stravaPost.OwnerId, stravaPost.ObjectId)
+    }
+    log.Printf("> returning 200 to strava")
+    return &events.APIGatewayProxyResponse{
+        StatusCode: 200,
+        Body: "webhook ok",
+    }, nil
+}
+
+func process_webhook_get(r events.APIGatewayProxyRequest) (*events.APIGatewayProxyResponse, error) {
+    log.Printf("verifying webhook subscription with strava\n")
+    switch is_app_subscribed() {
+    case true:
+        log.Printf("windspeed.app is already subscribed\n")
+        return &events.APIGatewayProxyResponse{
+            StatusCode: 200,
+            Body: "windspeed.app is already subscribed!",
+        }, nil
+    case false:
+        log.Printf("performing hub challenge with strava...\n")
+        hub_mode  := r.QueryStringParameters["hub.mode"]
+        hub_token := r.QueryStringParameters["hub.verify_token"]
+        if hub_mode == "subscribe" && hub_token == os.Getenv("STRAVA_VERIFY_TOKEN") {
+            log.Printf("hub challenge passed.\n")
+            return &events.APIGatewayProxyResponse{
+                StatusCode: 200,
+                Body: fmt.Sprintf("{ \"hub.challenge\":\"%s\" }", r.QueryStringParameters["hub.challenge"]),
+                Headers: map[string]string{"Content-Type": "application/json"},
+            }, nil
+        } else {
+            log.Printf("hub challenge failed, verification tokens do not match.\n")
+            return &events.APIGatewayProxyResponse{
+                StatusCode: 200,
+                Body: "app is not subscribed...",
+            }, nil
+        }
+    }
+    log.Printf("finished\n")
+    return nil, nil
+}
+
+func is_app_subscribed() bool {
+	subscriptions, err := strava.API.ListSubscriptions(context.Background())
+	if err != nil {
+		log.Printf("request failed: %s\n", err)
+		return false
+	}
+	return len(subscriptions) > 0 && subscriptions[0].Id != 0
+}
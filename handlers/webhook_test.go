@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"windspeed/helpers/strava"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func webhookRequest(t *testing.T, post StravaPost) events.APIGatewayProxyRequest {
+	t.Helper()
+	body, err := json.Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.APIGatewayProxyRequest{HTTPMethod: "POST", Body: string(body)}
+}
+
+func TestWebhookQueuesJobs(t *testing.T) {
+	store, api := setup(t)
+	created := StravaPost{AspectType: "create", EventTime: 1000, ObjectId: 5, ObjectType: "activity", OwnerId: 7}
+	updated := StravaPost{AspectType: "update", EventTime: 1060, ObjectId: 5, ObjectType: "activity", OwnerId: 7,
+		Updates: map[string]interface{}{"title": "Morning ride"}}
+	other := StravaPost{AspectType: "create", EventTime: 1000, ObjectId: 6, ObjectType: "activity", OwnerId: 7}
+	deleted := StravaPost{AspectType: "delete", EventTime: 1200, ObjectId: 5, ObjectType: "activity", OwnerId: 7}
+
+	tests := []struct {
+		name string
+		post StravaPost
+		jobs map[string]string
+	}{
+		{"created", created, map[string]string{"add_weather 7/5": strava.JobPending}},
+		{"redelivered", created, map[string]string{"add_weather 7/5": strava.JobPending}},
+		{"updated while pending", updated, map[string]string{"add_weather 7/5": strava.JobPending}},
+		{"other activity", other, map[string]string{"add_weather 7/5": strava.JobPending, "add_weather 7/6": strava.JobPending}},
+		{"deleted", deleted, map[string]string{"add_weather 7/6": strava.JobPending}},
+		{"deletion redelivered", deleted, map[string]string{"add_weather 7/6": strava.JobPending}},
+	}
+	for _, test := range tests {
+		resp, err := Webhook(webhookRequest(t, test.post))
+		if err != nil || resp.StatusCode != 200 {
+			t.Fatalf("%v: Webhook = %+v, %v, want 200", test.name, resp, err)
+		}
+		if jobs := jobsOf(store); !reflect.DeepEqual(jobs, test.jobs) {
+			t.Errorf("%v: jobs = %v, want %v", test.name, jobs, test.jobs)
+		}
+	}
+	if requests := api.Requests(); len(requests) != 0 {
+		t.Errorf("Webhook called Strava: %v", requests)
+	}
+}
+
+func TestWebhookRequeuesRunningJob(t *testing.T) {
+	store, _ := setup(t)
+	ctx := context.Background()
+	post := StravaPost{AspectType: "create", EventTime: 1000, ObjectId: 5, ObjectType: "activity", OwnerId: 7}
+	if _, err := Webhook(webhookRequest(t, post)); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	job, err := store.ClaimJob(ctx, now, now.Add(strava.VisibilityTimeout))
+	if err != nil {
+		t.Fatalf("failed to claim job: %v", err)
+	}
+
+	post.AspectType, post.EventTime = "update", 1060
+	if _, err := Webhook(webhookRequest(t, post)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompleteJob(ctx, job.Id); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, want := jobsOf(store), map[string]string{"add_weather 7/5": strava.JobPending}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+}
+
+func TestWebhookActivityNotFound(t *testing.T) {
+	store, _ := setup(t)
+	strava.AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+	post := StravaPost{AspectType: "create", EventTime: 1000, ObjectId: 5, ObjectType: "activity", OwnerId: 7}
+	if _, err := Webhook(webhookRequest(t, post)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The activity is gone by the time the job runs, so it is not retried.
+	strava.ProcessJobs(context.Background(), store, 10)
+	if jobs, want := jobsOf(store), map[string]string{"add_weather 7/5": strava.JobDead}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+}
+
+func TestWebhookDeauthorization(t *testing.T) {
+	store, api := setup(t)
+	ctx := context.Background()
+	strava.AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+	strava.AddUserSettings(store, 7, strava.Settings{StampTemplate: "{{.temp}}"})
+	if err := strava.EnqueueJob(store, strava.JobBackfill, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	post := StravaPost{AspectType: "update", EventTime: 1000, ObjectId: 7, ObjectType: "athlete", OwnerId: 7,
+		Updates: map[string]interface{}{"authorized": "false"}}
+	resp, err := Webhook(webhookRequest(t, post))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("Webhook = %+v, %v, want 200", resp, err)
+	}
+
+	// The webhook only queues the removal.
+	if requests := api.Requests(); len(requests) != 0 {
+		t.Errorf("Webhook called Strava: %v", requests)
+	}
+	want := map[string]string{"backfill 7/0": strava.JobPending, "unsubscribe 7/7": strava.JobPending}
+	if jobs := jobsOf(store); !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+
+	strava.ProcessJobs(context.Background(), store, 10)
+	if _, err := store.GetSubscriber(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscriber was not deleted: %v", err)
+	}
+	if _, err := store.GetSettings(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("settings were not deleted: %v", err)
+	}
+	if jobs := jobsOf(store); len(jobs) != 0 {
+		t.Errorf("jobs were not deleted: %v", jobs)
+	}
+}
+
+func TestWebhookStoreUnavailable(t *testing.T) {
+	setup(t)
+	strava.OpenStore = func(ctx context.Context) (strava.Store, error) {
+		return nil, errors.New("connection refused")
+	}
+	post := StravaPost{AspectType: "create", EventTime: 1000, ObjectId: 5, ObjectType: "activity", OwnerId: 7}
+	resp, err := Webhook(webhookRequest(t, post))
+	if err != nil || resp.StatusCode != 500 || resp.Body != "database unavailable" {
+		t.Errorf("Webhook = %+v, %v, want a 500 so Strava retries the event", resp, err)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"windspeed/helpers/strava"
+)
+
+// BatchSize caps the number of jobs run by a single invocation.
+const BatchSize int = 25
+
+// WorkerTimeout bounds a run of the worker, including the jobs it started,
+// below Netlify's 30 second limit for scheduled functions.
+const WorkerTimeout time.Duration = 25 * time.Second
+
+// Worker runs a batch of the queued jobs.
+func Worker(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, WorkerTimeout)
+	defer cancel()
+	store, err := openStore(ctx)
+	if err != nil {
+		return err
+	}
+	processed := strava.ProcessJobs(ctx, store, BatchSize)
+	log.Printf("> processed %v jobs\n", processed)
+	return nil
+}
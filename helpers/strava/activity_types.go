@@ -0,0 +1,83 @@
+package strava
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ActivityTypes are the outdoor sport types users can have stamped. Virtual
+// activities are not listed, so they are never stamped.
+var ActivityTypes = []string{
+	"Ride", "GravelRide", "MountainBikeRide", "EBikeRide", "EMountainBikeRide",
+	"Velomobile", "Handcycle", "Wheelchair", "Run", "TrailRun", "Walk", "Hike",
+	"Swim", "Rowing", "Kayaking", "Canoeing", "StandUpPaddling", "Surfing",
+	"Kitesurf", "Windsurf", "Sail", "AlpineSki", "BackcountrySki", "NordicSki",
+	"Snowboard", "Snowshoe", "IceSkate", "InlineSkate", "Skateboard",
+	"RollerSki", "Golf",
+}
+
+// IsActivityType reports whether t is one of ActivityTypes.
+func IsActivityType(t string) bool {
+	for _, activityType := range ActivityTypes {
+		if activityType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivityTypeLabel spells out a sport type, e.g. "E-Mountain Bike Ride".
+func ActivityTypeLabel(t string) string {
+	var sb strings.Builder
+	for i, r := range t {
+		switch {
+		case i == 1 && t[0] == 'E' && unicode.IsUpper(r):
+			sb.WriteRune('-')
+		case i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(rune(t[i-1])):
+			sb.WriteRune(' ')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// StampsActivityType reports whether the user wants activities of this sport
+// type stamped. Users who never picked any types get everything but virtual
+// activities, including types not listed in ActivityTypes.
+func (s Settings) StampsActivityType(activity Activity) bool {
+	t := activity.SportType
+	if t == "" {
+		t = activity.Type
+	}
+	if len(s.ActivityTypes) == 0 {
+		return !strings.HasPrefix(t, "Virtual")
+	}
+	for _, activityType := range s.ActivityTypes {
+		if activityType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ActivityTypeOption is a checkbox in the settings form.
+type ActivityTypeOption struct {
+	Value   string
+	Label   string
+	Checked bool
+}
+
+// ActivityTypeOptions lists every activity type, checking the selected ones
+// (or all of them if none are selected).
+func ActivityTypeOptions(selected []string) []ActivityTypeOption {
+	settings := Settings{ActivityTypes: selected}
+	options := make([]ActivityTypeOption, 0, len(ActivityTypes))
+	for _, t := range ActivityTypes {
+		options = append(options, ActivityTypeOption{
+			Value:   t,
+			Label:   ActivityTypeLabel(t),
+			Checked: settings.StampsActivityType(Activity{SportType: t}),
+		})
+	}
+	return options
+}
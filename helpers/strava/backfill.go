@@ -0,0 +1,129 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"windspeed/utils/weather"
+)
+
+// BackfillInterval is the pause between two slices of a backfill or restamp
+// run by the worker.
+const BackfillInterval time.Duration = 30 * time.Second
+
+// BackfillBudget is the share of each Strava rate limit budget backfills and
+// restamps may use. Each activity costs about three api calls, and the rest
+// of the budget is kept for newly uploaded activities.
+const BackfillBudget float64 = 0.5
+
+// BackfillRetryDelay is how long a backfill waits after a failure that is
+// likely to go away, such as a timeout or a Strava server error.
+const BackfillRetryDelay time.Duration = 15 * time.Minute
+
+// BackfillPageSize is the number of activities listed per Strava api call.
+const BackfillPageSize int = 30
+
+// Backfill states.
+const (
+	BackfillRunning string = "running"
+	BackfillDone    string = "done"
+)
+
+// Backfill is the checkpoint of a users backfill. Activities are processed
+// oldest first, and Checkpoint is the start time of the last one processed.
+type Backfill struct {
+	AthleteId  int64
+	After      int64
+	Before     int64
+	Checkpoint int64
+	Processed  int
+	Status     string
+}
+
+// StartBackfill resets the users checkpoint to the given date range. The
+// backfill is then run by RunBackfill, directly or through a JobBackfill.
+func StartBackfill(store Store, athleteId int64, after time.Time, before time.Time) error {
+	log.Printf("> starting backfill for strava user: %v, from %v to %v\n", athleteId, after.Format("2006-01-02"), before.Format("2006-01-02"))
+	backfill := Backfill{AthleteId: athleteId, After: after.Unix(), Before: before.Unix(), Checkpoint: after.Unix(), Status: BackfillRunning}
+	return store.StartBackfill(context.Background(), backfill)
+}
+
+// RunBackfill stamps the users past activities from the checkpoint onwards
+// until the range is done or the deadline has passed. It reports whether the
+// backfill is done; otherwise it can be resumed by calling it again.
+func RunBackfill(ctx context.Context, store Store, athleteId int64, deadline time.Time) (bool, error) {
+	backfill, err := store.GetBackfill(ctx, athleteId)
+	if err != nil {
+		return false, err
+	}
+	if backfill.Status == BackfillDone {
+		return true, nil
+	}
+
+	for time.Now().Before(deadline) {
+		if err := backfillPause(ctx, store); err != nil {
+			return false, err
+		}
+		tokens, err := userTokens(ctx, store, athleteId)
+		if err != nil {
+			return false, err
+		}
+
+		activities, err := API.ListActivities(ctx, tokens.AccessToken, backfill.Checkpoint, backfill.Before, 1, BackfillPageSize)
+		if err != nil {
+			return false, rateLimited(unauthorized(ctx, store, tokens, err))
+		}
+		if len(activities) == 0 {
+			backfill.Status = BackfillDone
+			log.Printf("> backfill done for strava user: %v, %v activities\n", athleteId, backfill.Processed)
+			return true, store.SaveBackfill(ctx, backfill)
+		}
+
+		for _, activity := range activities {
+			if time.Now().After(deadline) {
+				return false, nil
+			}
+			if err := backfillPause(ctx, store); err != nil {
+				return false, err
+			}
+			if err := AddWeatherDetails(ctx, store, athleteId, activity.Id); err != nil {
+				var deferred *DeferredError
+				if err := rateLimited(err); errors.As(err, &deferred) || errors.Is(err, ErrRevoked) {
+					return false, err
+				}
+				// Try the activity again later rather than losing it.
+				if errors.Is(err, weather.ErrTimeout) || isTransient(err) {
+					return false, &DeferredError{Until: time.Now().Add(BackfillRetryDelay), Err: err}
+				}
+				log.Printf("> backfill skipped activity %v: %v\n", activity.Id, err)
+			}
+
+			// Move the checkpoint past this activity, so it is not processed twice.
+			t, err := time.Parse(time.RFC3339, activity.StartDate)
+			if err != nil {
+				return false, err
+			}
+			backfill.Checkpoint = t.Unix()
+			backfill.Processed++
+			if err := store.SaveBackfill(ctx, backfill); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// backfillPause returns a *DeferredError once the recorded usage reaches
+// BackfillBudget, so long tasks wait for the next window.
+func backfillPause(ctx context.Context, store Store) error {
+	rateLimit, err := store.GetRateLimit(ctx)
+	if err != nil {
+		return nil
+	}
+	if until, ok := rateLimit.exhaustedUntil(time.Now(), BackfillBudget); ok {
+		return &DeferredError{Until: until, Err: errors.New("waiting for the strava rate limit budget")}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is where the Strava API and OAuth endpoints live.
+const DefaultBaseURL string = "https://www.strava.com"
+
+// DefaultTimeout bounds a single call to the Strava API.
+const DefaultTimeout time.Duration = 10 * time.Second
+
+// API is the client used by the helpers in this package. It can be replaced,
+// e.g. with one pointing at an httptest server. See ShareRateLimit for its
+// Limiter.
+var API = NewClient(&http.Client{Timeout: DefaultTimeout}, os.Getenv("STRAVA_BASE_URL"), os.Getenv("STRAVA_CLIENT_ID"), os.Getenv("STRAVA_CLIENT_SECRET"))
+
+// Client talks to the Strava API on behalf of windspeed.app. If a Limiter
+// is set, api calls are refused with a *RateLimitError once the apps budget
+// is nearly spent.
+type Client struct {
+	HTTP         *http.Client
+	BaseURL      string
+	ClientId     string
+	ClientSecret string
+	Limiter      RateLimiter
+}
+
+// APIError is returned when Strava answers with a non 2xx status code.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("strava responded with status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Authorization is Stravas answer to a successful code exchange.
+type Authorization struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
+	Athlete      struct {
+		ID        int64  `json:"id"`
+		FirstName string `json:"firstname"`
+		LastName  string `json:"lastname"`
+	} `json:"athlete"`
+}
+
+// Subscription is a webhook subscription of the app.
+type Subscription struct {
+	Id          int64  `json:"id"`
+	CallbackUrl string `json:"callback_url"`
+}
+
+// NewClient creates a client. An empty baseURL means DefaultBaseURL.
+func NewClient(httpClient *http.Client, baseURL string, clientId string, clientSecret string) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		HTTP:         httpClient,
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+	}
+}
+
+func (c *Client) GetActivity(ctx context.Context, accessToken string, activityId int64) (Activity, error) {
+	var activity Activity
+	err := c.do(ctx, "GET", fmt.Sprintf("/api/v3/activities/%v", activityId), accessToken, nil, &activity)
+	return activity, err
+}
+
+func (c *Client) GetActivityStreams(ctx context.Context, accessToken string, activityId int64) (ActivityStreams, error) {
+	var streams ActivityStreams
+	path := fmt.Sprintf("/api/v3/activities/%v/streams?keys=latlng,time&key_by_type=true", activityId)
+	err := c.do(ctx, "GET", path, accessToken, nil, &streams)
+	return streams, err
+}
+
+// ListActivities returns a page of the athletes activities that started
+// between after and before (unix times), oldest first.
+func (c *Client) ListActivities(ctx context.Context, accessToken string, after int64, before int64, page int, perPage int) ([]Activity, error) {
+	params := url.Values{}
+	params.Add("after", fmt.Sprintf("%d", after))
+	params.Add("before", fmt.Sprintf("%d", before))
+	params.Add("page", fmt.Sprintf("%d", page))
+	params.Add("per_page", fmt.Sprintf("%d", perPage))
+
+	var activities []Activity
+	err := c.do(ctx, "GET", "/api/v3/athlete/activities?"+params.Encode(), accessToken, nil, &activities)
+	return activities, err
+}
+
+// UpdateActivity sets the given fields of an activity, e.g. "description".
+func (c *Client) UpdateActivity(ctx context.Context, accessToken string, activityId int64, fields map[string]string) error {
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, "PUT", fmt.Sprintf("/api/v3/activities/%v", activityId), accessToken, payload, nil)
+}
+
+// RefreshToken exchanges a refresh token for a new set of tokens.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (Tokens, error) {
+	params := url.Values{}
+	params.Add("client_id", c.ClientId)
+	params.Add("client_secret", c.ClientSecret)
+	params.Add("refresh_token", refreshToken)
+	params.Add("grant_type", "refresh_token")
+
+	var tokens Tokens
+	err := c.postForm(ctx, "/oauth/token", params, &tokens)
+	return tokens, err
+}
+
+// ExchangeCode trades the code from the OAuth redirect for the athletes tokens.
+func (c *Client) ExchangeCode(ctx context.Context, code string) (Authorization, error) {
+	params := url.Values{}
+	params.Add("client_id", c.ClientId)
+	params.Add("client_secret", c.ClientSecret)
+	params.Add("code", code)
+	params.Add("grant_type", "authorization_code")
+
+	var authorization Authorization
+	err := c.postForm(ctx, "/oauth/token", params, &authorization)
+	return authorization, err
+}
+
+// Deauthorize revokes windspeed.apps access to the athletes account.
+func (c *Client) Deauthorize(ctx context.Context, accessToken string) error {
+	params := url.Values{}
+	params.Add("access_token", accessToken)
+	return c.postForm(ctx, "/oauth/deauthorize", params, nil)
+}
+
+func (c *Client) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	params := url.Values{}
+	params.Add("client_id", c.ClientId)
+	params.Add("client_secret", c.ClientSecret)
+
+	var subscriptions []Subscription
+	err := c.do(ctx, "GET", "/api/v3/push_subscriptions?"+params.Encode(), "", nil, &subscriptions)
+	return subscriptions, err
+}
+
+func (c *Client) postForm(ctx context.Context, path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.send(req, v)
+}
+
+// do sends a JSON request, authorized with the athletes access token if one
+// is given, and decodes the response into v (if not nil).
+func (c *Client) do(ctx context.Context, method string, path string, accessToken string, payload []byte, v interface{}) error {
+	if c.Limiter != nil {
+		if err := c.Limiter.Check(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", accessToken))
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, v)
+}
+
+func (c *Client) send(req *http.Request, v interface{}) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if rateLimit, ok := parseRateLimit(resp.Header, time.Now()); ok && c.Limiter != nil {
+		c.Limiter.Record(rateLimit)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(body, v)
+}
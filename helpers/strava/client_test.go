@@ -0,0 +1,125 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// testClient returns a client for an httptest server running handler.
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.Client(), srv.URL+"/", "client-id", "client-secret")
+}
+
+func TestGetActivity(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/v3/activities/5" || r.Header.Get("Authorization") != "Bearer access" {
+			http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"id":5,"name":"Morning Ride","description":"Club ride","private_note":"legs","start_date":"2024-05-01T07:00:00Z",
+			"start_latlng":[52.5,13.4],"sport_type":"GravelRide","type":"Ride","manual":false,"trainer":true,"kudos_count":3}`)
+	})
+
+	activity, err := client.GetActivity(context.Background(), "access", 5)
+	want := Activity{
+		Id:          5,
+		Name:        "Morning Ride",
+		Description: "Club ride",
+		PrivateNote: "legs",
+		StartDate:   "2024-05-01T07:00:00Z",
+		StartLatLng: [2]float64{52.5, 13.4},
+		SportType:   "GravelRide",
+		Type:        "Ride",
+		Trainer:     true,
+	}
+	if err != nil || activity != want {
+		t.Errorf("GetActivity = %+v, %v, want %+v", activity, err, want)
+	}
+}
+
+func TestUpdateActivity(t *testing.T) {
+	var got map[string]string
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/api/v3/activities/5" || r.Header.Get("Authorization") != "Bearer access" ||
+			r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"id":5}`)
+	})
+
+	fields := map[string]string{"description": "Club ride\n20.1°C", "private_note": ""}
+	if err := client.UpdateActivity(context.Background(), "access", 5, fields); err != nil {
+		t.Fatalf("UpdateActivity failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("sent %v, want %v", got, fields)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	var got url.Values
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/oauth/token" || r.Header.Get("Authorization") != "" {
+			http.Error(w, `{"message":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		r.ParseForm()
+		got = r.PostForm
+		fmt.Fprint(w, `{"token_type":"Bearer","access_token":"new-access","refresh_token":"new-refresh","expires_at":1714550400,"expires_in":21600}`)
+	})
+
+	tokens, err := client.RefreshToken(context.Background(), "refresh")
+	if want := (Tokens{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresAt: 1714550400}); err != nil || tokens != want {
+		t.Errorf("RefreshToken = %+v, %v, want %+v", tokens, err, want)
+	}
+	want := url.Values{
+		"client_id":     {"client-id"},
+		"client_secret": {"client-secret"},
+		"refresh_token": {"refresh"},
+		"grant_type":    {"refresh_token"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %v, want %v", got, want)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusBadRequest, `{"message":"Bad Request","errors":[{"resource":"RefreshToken","field":"refresh_token","code":"invalid"}]}`},
+		{http.StatusUnauthorized, `{"message":"Authorization Error"}`},
+		{http.StatusNotFound, `{"message":"Record Not Found"}`},
+		{http.StatusTooManyRequests, `{"message":"Rate Limit Exceeded"}`},
+		{http.StatusInternalServerError, `Internal Server Error`},
+	}
+	for _, test := range tests {
+		client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, test.body)
+		})
+
+		_, err := client.GetActivity(context.Background(), "access", 5)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status || apiErr.Body != test.body {
+			t.Errorf("GetActivity = %v, want an *APIError with status %v and body %q", err, test.status, test.body)
+		}
+		_, err = client.RefreshToken(context.Background(), "refresh")
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+			t.Errorf("RefreshToken = %v, want an *APIError with status %v", err, test.status)
+		}
+	}
+}
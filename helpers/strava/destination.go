@@ -0,0 +1,67 @@
+package strava
+
+import (
+	"strings"
+
+	"windspeed/utils/weather"
+)
+
+// Where on the activity the stamp is written.
+const (
+	DestinationDescription string = "description"
+	DestinationName        string = "name"
+	DestinationPrivateNote string = "private_note"
+)
+
+var Destinations = []string{DestinationDescription, DestinationName, DestinationPrivateNote}
+
+// IsDestination reports whether d is one of Destinations.
+func IsDestination(d string) bool {
+	for _, destination := range Destinations {
+		if destination == d {
+			return true
+		}
+	}
+	return false
+}
+
+// destination defaults to the description for users who never picked one.
+func (s Settings) destination() string {
+	if IsDestination(s.Destination) {
+		return s.Destination
+	}
+	return DestinationDescription
+}
+
+// renderStamp renders the report for the users destination. Names only
+// get a short summary of the wind.
+func (s Settings) renderStamp(report weather.Report) (string, error) {
+	if s.destination() == DestinationName {
+		return weather.ShortStamp(report, s.Units), nil
+	}
+	return weather.RenderStamp(report, s.Units, s.StampTemplate)
+}
+
+// field points to the activity text the destination refers to.
+func (a *Activity) field(destination string) *string {
+	switch destination {
+	case DestinationName:
+		return &a.Name
+	case DestinationPrivateNote:
+		return &a.PrivateNote
+	}
+	return &a.Description
+}
+
+// appendStamp adds the stamp to the text, behind the name or on a line of
+// its own for the description and private note.
+func appendStamp(text string, stamp string, destination string) string {
+	text = strings.TrimRight(text, " ")
+	switch {
+	case text == "":
+		return stamp
+	case destination == DestinationName:
+		return text + " " + stamp
+	}
+	return text + "\n" + stamp
+}
@@ -0,0 +1,178 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Kinds of jobs processed by the worker.
+const (
+	JobAddWeather  string = "add_weather"
+	JobBackfill    string = "backfill"
+	JobRestamp     string = "restamp"
+	JobUnsubscribe string = "unsubscribe"
+)
+
+// Job states. Jobs that run out of attempts are kept as "dead" for inspection.
+const (
+	JobPending string = "pending"
+	JobDone    string = "done"
+	JobDead    string = "dead"
+)
+
+// MaxAttempts is how often a job is tried before it is dead-lettered.
+const MaxAttempts int = 8
+
+// VisibilityTimeout hides a claimed job from other workers. If the worker
+// dies before finishing, the job becomes visible again afterwards.
+const VisibilityTimeout time.Duration = 2 * time.Minute
+
+// JobTimeout bounds a single job, including the Strava and weather calls
+// still in flight at the end of its slice. A job is only started if it can
+// run that long before the worker's deadline.
+const JobTimeout time.Duration = 15 * time.Second
+
+// JobSlice is how long a single job works on a long task, such as a
+// backfill, before handing the rest over to a later run.
+const JobSlice time.Duration = 10 * time.Second
+
+// ErrPermanent marks failures that retrying won't fix, such as a rejected
+// api key. Jobs failing with it are dead-lettered straight away.
+var ErrPermanent = errors.New("permanent failure")
+
+// DeferredError asks the worker to run the job again after Until, without
+// counting the attempt, e.g. when a backfill has more work left.
+type DeferredError struct {
+	Until time.Time
+	Err   error
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("deferred until %v: %v", e.Until.Format(time.RFC3339), e.Err)
+}
+
+func (e *DeferredError) Unwrap() error {
+	return e.Err
+}
+
+// Job is a unit of work on a Strava object, e.g. stamping an activity.
+type Job struct {
+	Id          int64
+	Kind        string
+	AthleteId   int64
+	ObjectId    int64
+	Attempts    int
+	MaxAttempts int
+}
+
+// EnqueueJob stores a job to be run as soon as possible. A pending job for
+// the same object is not duplicated.
+func EnqueueJob(store Store, kind string, athleteId int64, objectId int64) error {
+	log.Printf("> enqueueing %v job for strava user: %v, object = %v\n", kind, athleteId, objectId)
+	return store.EnqueueJob(context.Background(), kind, athleteId, objectId, time.Now())
+}
+
+// ProcessJobs runs due jobs until there are none left, limit jobs have been
+// run, or there is no time left to run another one before the deadline of
+// ctx. Each job runs with the deadline of ctx, capped to JobTimeout. It
+// returns the number of jobs run.
+func ProcessJobs(ctx context.Context, store Store, limit int) int {
+	processed := 0
+	for processed < limit {
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(JobTimeout).After(deadline) {
+			break
+		}
+		now := time.Now()
+		job, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout))
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			log.Printf("> failed to claim job: %v\n", err)
+			break
+		}
+		processed++
+
+		jobCtx, cancel := context.WithTimeout(ctx, JobTimeout)
+		err = rateLimited(runJob(jobCtx, store, job))
+		cancel()
+
+		// Recorded even if ctx ended while the job ran.
+		var deferred *DeferredError
+		if errors.As(err, &deferred) {
+			deferJob(context.Background(), store, job, deferred)
+		} else if err != nil {
+			failJob(context.Background(), store, job, err)
+		} else if err := store.CompleteJob(context.Background(), job.Id); err != nil {
+			log.Printf("> failed to complete job %v: %v\n", job.Id, err)
+		}
+	}
+	return processed
+}
+
+func runJob(ctx context.Context, store Store, job Job) error {
+	switch job.Kind {
+	case JobAddWeather:
+		return AddWeatherDetails(ctx, store, job.AthleteId, job.ObjectId)
+	case JobBackfill:
+		done, err := RunBackfill(ctx, store, job.AthleteId, time.Now().Add(JobSlice))
+		if err == nil && !done {
+			err = &DeferredError{Until: time.Now().Add(BackfillInterval), Err: errors.New("backfill has more activities")}
+		}
+		return err
+	case JobRestamp:
+		done, err := RestampActivities(ctx, store, job.AthleteId, time.Now().Add(JobSlice))
+		if err == nil && !done {
+			err = &DeferredError{Until: time.Now().Add(BackfillInterval), Err: errors.New("more stamps to re-render")}
+		}
+		return err
+	case JobUnsubscribe:
+		done, err := RemoveUser(ctx, store, job.AthleteId, time.Now().Add(JobSlice))
+		if err == nil && !done {
+			err = &DeferredError{Until: time.Now().Add(BackfillInterval), Err: errors.New("more stamps to remove")}
+		}
+		return err
+	}
+	return fmt.Errorf("unknown job kind: %q", job.Kind)
+}
+
+// deferJob puts the job back without using up one of its attempts.
+func deferJob(ctx context.Context, store Store, job Job, deferred *DeferredError) {
+	log.Printf("> job %v %v\n", job.Id, deferred)
+	if err := store.DeferJob(ctx, job.Id, deferred.Until); err != nil {
+		log.Printf("> failed to defer job %v: %v\n", job.Id, err)
+	}
+}
+
+// failJob schedules the job to be retried with exponential backoff, or
+// dead-letters it once it has run out of attempts, the user revoked access or
+// the failure is permanent.
+func failJob(ctx context.Context, store Store, job Job, jobErr error) {
+	status := JobPending
+	runAt := time.Now().Add(backoff(job.Attempts))
+	if job.Attempts >= job.MaxAttempts || errors.Is(jobErr, ErrRevoked) || errors.Is(jobErr, ErrPermanent) {
+		status = JobDead
+	}
+	log.Printf("> job %v failed (attempt %v of %v, now %v): %v\n", job.Id, job.Attempts, job.MaxAttempts, status, jobErr)
+
+	if err := store.FailJob(ctx, job.Id, status, runAt, jobErr.Error()); err != nil {
+		log.Printf("> failed to reschedule job %v: %v\n", job.Id, err)
+	}
+}
+
+// backoff doubles the wait after each attempt, starting at 30 seconds and
+// capped at 6 hours.
+func backoff(attempts int) time.Duration {
+	wait := 30 * time.Second
+	for i := 1; i < attempts && wait < 6*time.Hour; i++ {
+		wait *= 2
+	}
+	if wait > 6*time.Hour {
+		wait = 6 * time.Hour
+	}
+	return wait
+}
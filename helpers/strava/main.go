@@ -1,16 +1,13 @@
 package strava
 
 import (
-    "bytes"
+    "context"
     "database/sql"
     "encoding/json"
+    "errors"
 	"fmt"
-    "io"
 	"log"
     "net/http"
-    "net/url"
-	"os"
-    "strings"
     "time"
     
 	"windspeed/utils/database"
@@ -19,16 +16,42 @@ import (
 
 const DB_SCHEMA string = "strava"
 
+// WeatherTimeout bounds the weather lookups made for a single activity,
+// leaving time within JobTimeout for the Strava calls around them.
+const WeatherTimeout time.Duration = 10 * time.Second
+
+var weatherClient = weather.NewClient()
+
+
 type Activity struct {
     Description     string       `json:"description"`
     Id              int64        `json:"id"`
     Manual          bool         `json:"manual" default:"false"`
+    Name            string       `json:"name"`
+    PrivateNote     string       `json:"private_note"`
     StartDate       string       `json:"start_date"`
     StartLatLng     [2]float64   `json:"start_latlng"`
+    SportType       string       `json:"sport_type"`
     Trainer         bool         `json:"trainer" default:"true"`
     Type            string       `json:"type"`
 }
 
+type ActivityStreams struct {
+    LatLng struct {
+        Data    [][2]float64    `json:"data"`
+    }                           `json:"latlng"`
+    Time struct {
+        Data    []int64         `json:"data"`
+    }                           `json:"time"`
+}
+
+type Settings struct {
+    Units           weather.Units
+    StampTemplate   string
+    ActivityTypes   []string
+    Destination     string
+}
+
 type Tokens struct {
     AccessToken    string    `json:"access_token"`
     AthleteId      int64
@@ -36,10 +59,8 @@ type Tokens struct {
     RefreshToken   string    `json:"refresh_token"`
 }
 
-func AddNewUser(athleteId int64, accessToken string, refreshToken string, expiresAt int64) {
+func AddNewUser(store Store, athleteId int64, accessToken string, refreshToken string, expiresAt int64) {
 	log.Printf("adding new strava user: %v\n", athleteId)
-	db := database.Connect()
-	defer db.Close()
 
     tokens := Tokens{
         AccessToken:  accessToken,
@@ -48,153 +69,241 @@ func AddNewUser(athleteId int64, accessToken string, refreshToken string, expire
         RefreshToken: refreshToken,
     }
 
-    updateUserTokens(db, tokens)
-    database.AddEvent(fmt.Sprintf("%d", athleteId), "strava", database.NewUser, db)
+    ctx := context.Background()
+    if err := updateUserTokens(ctx, store, tokens); err != nil {
+        log.Printf("> %v\n", err)
+        return
+    }
+    if err := store.AddEvent(ctx, athleteId, database.NewUser); err != nil {
+        log.Printf("db-err: %s\n", err)
+    }
 }
 
-func updateUserTokens(db *sql.DB, tokens Tokens) {
-    sql := `INSERT INTO %s.%s.subscribers (id, access_token, refresh_token, expires_at) VALUES($1, $2, $3, $4) ON CONFLICT (id) DO UPDATE SET access_token = $2, refresh_token = $3, expires_at = $4;`
-	stmt, err := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-	if err != nil {
-		log.Printf("> failed to prepare insert: %v", err)
-	}
-	result, err := stmt.Exec(tokens.AthleteId, tokens.AccessToken, tokens.RefreshToken, tokens.ExpiresAt)
-	if err != nil {
-		log.Printf("> db-err: %s\n", err)
-	}
-    rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 1 {
-		log.Printf("> successfully updated tokens for strava user \"%v\"\n", tokens.AthleteId)
-	} else {
-		log.Printf("> failed to update tokens for strava user \"%v\"\n", tokens.AthleteId)
-	}
+// SignInUser stores the fresh tokens of a user signing in again to manage
+// their settings. It reports false for athletes who are not subscribed.
+func SignInUser(store Store, athleteId int64, accessToken string, refreshToken string, expiresAt int64) (bool, error) {
+    ctx := context.Background()
+    if _, err := store.GetSubscriber(ctx, athleteId); err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return false, nil
+        }
+        return false, err
+    }
+
+    log.Printf("strava user signed in: %v\n", athleteId)
+    tokens := Tokens{
+        AccessToken:  accessToken,
+        AthleteId:    athleteId,
+        ExpiresAt:    expiresAt,
+        RefreshToken: refreshToken,
+    }
+    return true, updateUserTokens(ctx, store, tokens)
 }
 
-func AddUserSettings(athleteId int64, units string) {
+func AddUserSettings(store Store, athleteId int64, settings Settings) {
     log.Printf("adding user settings\n")
-    db := database.Connect()
-	defer db.Close()
-
-    sql := `INSERT INTO %s.%s.settings (id, units) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET units = $2;`
-	stmt, _ := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-    
-    result, err := stmt.Exec(athleteId, units)
-	if err != nil {
-		log.Printf("db-err: %s\n", err)
-	}
-    rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 1 {
-		log.Printf("successfully added settings for user \"%v\" to \"%v.settings\"\n", athleteId, DB_SCHEMA)
-	} else {
-		log.Printf("failed to add user settings for \"%v\" to \"%v.subscribers\"\n", athleteId, DB_SCHEMA)
-	}
+    if err := store.SaveSettings(context.Background(), athleteId, settings); err != nil {
+        log.Printf("failed to add user settings for \"%v\": %v\n", athleteId, err)
+        return
+    }
+    log.Printf("successfully added settings for user \"%v\" to \"%v.settings\"\n", athleteId, DB_SCHEMA)
 }
 
-func DeleteUser(athleteId int64) {
+// DeleteUser deletes everything stored about the user. Their stamps are left
+// on Strava, see RemoveUser.
+func DeleteUser(store Store, athleteId int64) {
     log.Printf("> remove strava user: %v\n", athleteId)
-	db := database.Connect()
-	defer db.Close()
-    deleteSubscriber(db, athleteId)
-    deleteSettings(db, athleteId)
-    database.AddEvent(fmt.Sprintf("%d", athleteId), "strava", database.DeleteUser, db)
+    ctx := context.Background()
+
+    // Strava confirms deauthorizations we asked for, see UnsubscribeUser.
+    if _, err := store.GetSubscriber(ctx, athleteId); errors.Is(err, sql.ErrNoRows) {
+        log.Printf("> strava user %v was already removed\n", athleteId)
+        return
+    }
+    deletions := []func(context.Context, int64) error{
+        store.DeleteStamps,
+        store.DeleteSubscriber,
+        store.DeleteSettings,
+        store.DeleteBackfill,
+        store.DeletePendingJobs,
+    }
+    for _, deletion := range deletions {
+        if err := deletion(ctx, athleteId); err != nil {
+            log.Printf("db-err: %s\n", err)
+        }
+    }
+    if err := store.AddEvent(ctx, athleteId, database.DeleteUser); err != nil {
+        log.Printf("db-err: %s\n", err)
+    }
 }
 
-func deleteSubscriber(db *sql.DB, athleteId int64) {
-    sql := `DELETE FROM %s.%s.subscribers WHERE id = $1`
-	stmt, err := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-	if err != nil {
-		log.Printf("> failed to prepare insert: %v", err)
-	}
-	result, err := stmt.Exec(athleteId)
-	if err != nil {
-		log.Printf("db-err: %s\n", err)
-	}
-    rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 1 {
-		log.Printf("successfully removed user \"%v\" from \"%v.subscribers\"\n", athleteId, DB_SCHEMA)
-	} else {
-		log.Printf("failed to remove user \"%v\" from \"%v.subscribers\"\n", athleteId, DB_SCHEMA)
-	}
+// UnsubscribeUser queues the removal of a user who asked to leave, see
+// RemoveUser. Deauthorizations reported by Strava's webhook queue the same
+// job.
+func UnsubscribeUser(store Store, athleteId int64) error {
+    log.Printf("> unsubscribe strava user: %v\n", athleteId)
+    return EnqueueJob(store, JobUnsubscribe, athleteId, 0)
 }
 
-func deleteSettings(db *sql.DB, athleteId int64) {
-    sql := `DELETE FROM %s.%s.settings WHERE id = $1`
-	stmt, err := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-	if err != nil {
-		log.Printf("> failed to prepare insert: %v", err)
-	}
-	result, err := stmt.Exec(athleteId)
-	if err != nil {
-		log.Printf("db-err: %s\n", err)
-	}
-    rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 1 {
-		log.Printf("successfully removed user \"%v\" from \"%v.settings\"\n", athleteId, DB_SCHEMA)
-	} else {
-		log.Printf("failed to remove user \"%v\" from \"%v.settings\"\n", athleteId, DB_SCHEMA)
-	}
+// RemoveUser strips the stamps of a user who asked to leave, then revokes
+// windspeed.apps access with Strava and deletes the user. Users who already
+// revoked access are deleted all the same. It reports whether the user is
+// gone; otherwise it can be resumed by calling it again.
+func RemoveUser(ctx context.Context, store Store, athleteId int64, deadline time.Time) (bool, error) {
+    log.Printf("> removing strava user: %v\n", athleteId)
+
+    if _, err := store.GetSubscriber(ctx, athleteId); errors.Is(err, sql.ErrNoRows) {
+        log.Printf("> strava user %v was already removed\n", athleteId)
+        return true, nil
+    }
+    done, err := removeStamps(ctx, store, athleteId, deadline)
+    if !done && !errors.Is(err, ErrRevoked) {
+        return false, err
+    }
+
+    tokens, err := userTokens(ctx, store, athleteId)
+    if err != nil && !errors.Is(err, ErrRevoked) {
+        return false, err
+    }
+    if err == nil {
+        err = API.Deauthorize(ctx, tokens.AccessToken)
+        var apiErr *APIError
+        if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+            err = nil
+        }
+        if err != nil {
+            return false, fmt.Errorf("failed to deauthorize strava user %v: %w", athleteId, err)
+        }
+    }
+    DeleteUser(store, athleteId)
+    return true, nil
 }
 
-func getUserUnits(db *sql.DB, athleteId int64) string {
-    sql := `SELECT units FROM %s.%s.settings WHERE id = $1`
-	stmt, _ := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-    
-    var units string
-    if err := stmt.QueryRow(athleteId).Scan(&units); err != nil {
-        log.Printf("> error getting strava user units: %v", err)
-        units = "imperial"
+// getUserSettings returns the users settings, or the defaults if they can't
+// be read.
+func getUserSettings(ctx context.Context, store Store, athleteId int64) Settings {
+    settings, err := store.GetSettings(ctx, athleteId)
+    if err != nil {
+        log.Printf("> error getting strava user settings: %v", err)
+        return Settings{Units: weather.Imperial}
+    }
+    return settings
+}
+
+// GetUserSettings returns the users settings as they would be applied, with
+// the defaults filled in for those they never picked.
+func GetUserSettings(store Store, athleteId int64) (Settings, error) {
+    settings, err := store.GetSettings(context.Background(), athleteId)
+    if errors.Is(err, sql.ErrNoRows) {
+        settings, err = Settings{Units: weather.Imperial}, nil
+    }
+    if err != nil {
+        return Settings{}, err
     }
-    return units
+    if settings.StampTemplate == "" {
+        settings.StampTemplate = weather.DefaultTemplate
+    }
+    settings.Destination = settings.destination()
+    return settings, nil
 }
 
-func AddWeatherDetails(athleteId int64, activityId int64) {
+// AddWeatherDetails stamps an activity with the weather along its route. An
+// error means the activity was not stamped and the attempt may be retried.
+func AddWeatherDetails(ctx context.Context, store Store, athleteId int64, activityId int64) error {
     log.Printf("> adding weather details for strava user: %v, activity = %v\n", athleteId, activityId)
     tStartMs := time.Now().UnixMilli()
 
-	db := database.Connect()
-	defer db.Close()
+    tokens, err := userTokens(ctx, store, athleteId)
+    if err != nil {
+        return err
+    }
 
-    oldTokens := getUserTokens(db, athleteId)
-    tokens := refreshUserTokens(oldTokens)
-    
-    // persist latest user tokens (if necessary)
-    if tokens.ExpiresAt > oldTokens.ExpiresAt {
-        log.Printf("> persisting updated tokens\n")
-        updateUserTokens(db, tokens)
+    log.Print("getting user activity\n")
+    activity, err := API.GetActivity(ctx, tokens.AccessToken, activityId)
+    var apiErr *APIError
+    if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+        // Deleted, or made private to the app, since the event was sent.
+        return fmt.Errorf("activity %v not found: %w: %w", activityId, ErrPermanent, err)
+    }
+    if err != nil {
+        err = unauthorized(ctx, store, tokens, err)
+        return fmt.Errorf("error getting activity %v: %w", activityId, err)
+    }
+
+    stamped, err := store.IsStamped(ctx, activityId)
+    if err != nil {
+        return fmt.Errorf("error checking stamps of activity %v: %w", activityId, err)
     }
 
-    activity := getActivity(activityId, tokens)
+    // retreive users prefered units, stamp format and activity types
+    settings := getUserSettings(ctx, store, athleteId)
 
     // only add weather details for some activities that don't already have one...
-    if activity.Manual == true || activity.Trainer == true || activity.Type == "VirtualRide" {
+    if activity.Manual == true || activity.Trainer == true {
         log.Printf("Activity %v was manually created or indoor. Skipping...\n", activityId)
-    } else if strings.Contains(activity.Description, "°C") || strings.Contains(activity.Description, "°F") {
+    } else if !settings.StampsActivityType(activity) {
+        log.Printf("Activity %v is a %v, which the user does not stamp. Skipping...\n", activityId, activity.SportType)
+    } else if stamped {
+        log.Printf("Activity %v has already been stamped\n", activityId)
+    } else if weather.HasStamp(activity.Description) || weather.HasStamp(activity.PrivateNote) || weather.HasStamp(activity.Name) {
         log.Printf("Activity %v already has weather information\n", activityId)
     } else if activity.StartLatLng == [2]float64{} {
         log.Printf("No position present for activity %v\n", activityId) 
     } else if activity.StartLatLng != [2]float64{} {
-        // retreive users prefered units
-        userUnits := getUserUnits(db, athleteId)
-
         // parse activity start time into a time object
         t, err := time.Parse(time.RFC3339, activity.StartDate)
         if err != nil {
-            log.Printf("Failed to parse activity start time...\n")
+            log.Printf("Failed to parse activity start time: %v\n", err)
+            return nil
+        }
+
+        // Sample the weather along the route, falling back to the start position
+        route := getActivityRoute(ctx, activity.Id, t.Unix(), tokens)
+        if len(route) == 0 {
+            route = weather.Route{{Lat: activity.StartLatLng[0], Lng: activity.StartLatLng[1], Time: t.Unix()}}
         }
 
-        // Generate weather stamp
-        weatherStamp := weather.CreateStamp(activity.StartLatLng[0], activity.StartLatLng[1], t.Unix(), userUnits)
+        // Generate weather stamp, leaving the activity untouched if no provider could help
+        weatherCtx, cancel := context.WithTimeout(ctx, WeatherTimeout)
+        defer cancel()
+        report, err := weatherClient.Report(weatherCtx, route)
+        // Every provider has to agree before the activity is given up on or
+        // put off until tomorrow, otherwise the job is simply retried.
+        switch {
+        case weather.AllFailed(err, weather.ErrQuotaExceeded):
+            // Try again once the daily quota has been reset.
+            err = fmt.Errorf("weather quota exceeded, activity %v was not stamped: %w", activityId, err)
+            return &DeferredError{Until: time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour), Err: err}
+        case weather.AllFailed(err, weather.ErrBadKey):
+            return fmt.Errorf("weather api key rejected, activity %v was not stamped: %w: %w", activityId, ErrPermanent, err)
+        case weather.AllFailed(err, weather.ErrBadKey, weather.ErrNoData):
+            return fmt.Errorf("no weather data for activity %v: %w: %w", activityId, ErrPermanent, err)
+        case errors.Is(err, weather.ErrTimeout):
+            return fmt.Errorf("weather lookup timed out for activity %v: %w", activityId, err)
+        case err != nil:
+            return fmt.Errorf("weather lookup failed for activity %v: %w", activityId, err)
+        }
+        weatherStamp, err := settings.renderStamp(report)
+        if err != nil {
+            return fmt.Errorf("failed to render stamp for activity %v: %w", activityId, err)
+        }
         log.Printf("weather stamp: \"%v\"\n", weatherStamp)
         
-        // Add weather stamp to existing activity description
-        if activity.Description != "" {
-            activity.Description = strings.TrimRight(activity.Description, " ") + "\n" + weatherStamp
-        } else {
-            activity.Description = weatherStamp
-        }
+        // Add weather stamp to the description, name or private note
+        destination := settings.destination()
+        field := activity.field(destination)
+        *field = appendStamp(*field, weatherStamp, destination)
 
         // Update Strava activity
-        modifyActivity(activity, tokens)
+        if err := modifyActivity(ctx, activity, tokens, destination); err != nil {
+            return fmt.Errorf("error updating activity %v: %w", activityId, err)
+        }
+
+        // Remember the stamp, so it can be re-rendered or removed later
+        stamp := Stamp{ActivityId: activityId, AthleteId: athleteId, Destination: destination, Text: weatherStamp, Report: report}
+        if err := store.SaveStamp(ctx, stamp); err != nil {
+            log.Printf("> failed to record stamp for activity %v: %v\n", activityId, err)
+        }
         tDelta := time.Now().UnixMilli() - tStartMs
 
         // Record event
@@ -203,134 +312,49 @@ func AddWeatherDetails(athleteId int64, activityId int64) {
             "event_time":  t.Unix(),
             "lat": activity.StartLatLng[0],
             "lng": activity.StartLatLng[1],
+            "samples": len(route.Samples()),
             "weather_stamp": weatherStamp,
             "duration": tDelta,
         }
         jsonBytes, _ := json.Marshal(event)
         jsonString := string(jsonBytes)
         log.Printf("> %v\n", jsonString)
-        database.AddEvent(fmt.Sprintf("%d", athleteId), "strava", jsonString, db)
+        if err := store.AddEvent(ctx, athleteId, jsonString); err != nil {
+            log.Printf("db-err: %s\n", err)
+        }
 
         log.Printf("> finished in: %v ms\n", time.Now().UnixMilli() - tStartMs)
     }
+    return nil
 }
 
-func modifyActivity(activity Activity, tokens Tokens) {
+// modifyActivity sends the given destinations (description, name or
+// private note) of the activity to Strava.
+func modifyActivity(ctx context.Context, activity Activity, tokens Tokens, destinations ...string) error {
     log.Printf("> modifying activity: %v for user: %v\n", activity.Id, tokens.AthleteId)
-
-    // create payload
-    payload := map[string]string{
-        "description": activity.Description,
-    }
-    
-    // set the payload
-    jsonPayload, err := json.Marshal(payload)
-    if err != nil {
-        panic(err)
-    }
-
-     // Create a new HTTP request
-    url := fmt.Sprintf("https://www.strava.com/api/v3/activities/%v", activity.Id)
-    req, err := http.NewRequest("PUT", url, bytes.NewReader(jsonPayload))
-    if err != nil {
-        panic(err)
-    }
-
-    // Set the Authorization header
-    req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", tokens.AccessToken))
-    req.Header.Set("Content-Type", "application/json")
-    
-    // Send the request
-    resp, err := http.DefaultClient.Do(req)
-    if err != nil {
-        log.Printf("> error sending put request to strava: %v", err)
-    }
-
-    // Check the response status code.
-    log.Printf("> response code: %v\n", resp.StatusCode)
-    if resp.StatusCode != http.StatusOK {
-        log.Printf("unexpected response status code: %d", resp.StatusCode)
+    fields := map[string]string{}
+    for _, destination := range destinations {
+        fields[destination] = *activity.field(destination)
     }
+    return API.UpdateActivity(ctx, tokens.AccessToken, activity.Id, fields)
 }
-    
-func getActivity(activityId int64, tokens Tokens) Activity {
-
-    log.Print("getting user activity\n")
-    
-     // Create a new HTTP request
-    req, err := http.NewRequest("GET", fmt.Sprintf("https://www.strava.com/api/v3/activities/%v", activityId), nil)
-    if err != nil {
-        panic(err)
-    }
-
-    // Set the Authorization header
-    req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", tokens.AccessToken))
-
-    // Send the request
-    resp, err := http.DefaultClient.Do(req)
-    if err != nil {
-        panic(err)
-    }
-    defer resp.Body.Close()
 
-    // Read the response body.
-    body, err := io.ReadAll(resp.Body)
+func getActivityRoute(ctx context.Context, activityId int64, startTime int64, tokens Tokens) weather.Route {
+    log.Print("getting activity streams\n")
+    streams, err := API.GetActivityStreams(ctx, tokens.AccessToken, activityId)
     if err != nil {
-        panic(err)
+        log.Printf("> error getting activity streams: %v\n", err)
+        return nil
     }
-
-    // Munge response body.
-    var activity Activity
-    _ = json.Unmarshal(body, &activity)
-    return activity
-}
-
-func getUserTokens(db *sql.DB, athleteId int64) Tokens {
-    log.Printf("> getting user tokens\n")
-    sql := `SELECT id, access_token, refresh_token, expires_at FROM %s.%s.subscribers WHERE id = $1 LIMIT 1`
-    stmt, _ := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE"), DB_SCHEMA))
-    
-    var tokens Tokens
-    if err := stmt.QueryRow(athleteId).Scan(&tokens.AthleteId, &tokens.AccessToken, &tokens.RefreshToken, &tokens.ExpiresAt); err != nil {
-        log.Printf("> error getting user tokens: %v\n", err)
+    if len(streams.LatLng.Data) != len(streams.Time.Data) {
+        log.Printf("> latlng and time streams have different lengths\n")
+        return nil
     }
-    return tokens
-}
-
-func refreshUserTokens(tokens Tokens) Tokens {
 
-    if tokens.ExpiresAt > time.Now().Unix() {
-        log.Printf("> current user tokens are still valid\n")
-        return tokens
-    } else {
-        log.Printf("> tokens have expired. contacting strava for latest user tokens\n")
-        
-        // refresh tokens
-		params := url.Values{}
-		params.Add("client_id", os.Getenv("STRAVA_CLIENT_ID"))
-		params.Add("client_secret", os.Getenv("STRAVA_CLIENT_SECRET"))
-		params.Add("refresh_token", tokens.RefreshToken)
-		params.Add("grant_type",  "refresh_token")
-		resp, err := http.PostForm("https://www.strava.com/oauth/token", params)
-		if err != nil {
-			log.Printf("error refreshing user tokens from strava")
-		}
-		defer resp.Body.Close()
-
-		// parse response from strava
-        body, err := io.ReadAll(resp.Body)
-        if err != nil {
-          // handle error
-        }
-		var refreshedTokens Tokens
-		err = json.Unmarshal(body, &refreshedTokens)
-		if err != nil {
-            log.Printf("> token unmarshal error: %v", err)
-		}
-        refreshedTokens.AthleteId = tokens.AthleteId
-        if refreshedTokens.ExpiresAt > tokens.ExpiresAt {
-            log.Printf("> tokens have been refreshed\n")
-        }
-        return refreshedTokens
+    // Time stream holds seconds elapsed since the start of the activity.
+    route := make(weather.Route, 0, len(streams.LatLng.Data))
+    for i, latlng := range streams.LatLng.Data {
+        route = append(route, weather.Point{Lat: latlng[0], Lng: latlng[1], Time: startTime + streams.Time.Data[i]})
     }
+    return route
 }
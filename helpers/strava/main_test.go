@@ -0,0 +1,15 @@
+package strava
+
+import (
+	"encoding/base64"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestMain seals the tokens stored by the tests with a test key, see
+// tokenKeys.
+func TestMain(m *testing.M) {
+	os.Setenv("TOKEN_KEYS", "test:"+base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	os.Exit(m.Run())
+}
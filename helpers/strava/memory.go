@@ -0,0 +1,378 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps everything in memory, for tests and local development.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu            sync.Mutex
+	subscribers   map[int64]Subscriber
+	settings      map[int64]Settings
+	events        []MemoryEvent
+	webhookEvents map[WebhookEvent]bool
+	jobs          []*memoryJob
+	nextJobId     int64
+	backfills     map[int64]Backfill
+	stamps        map[int64]Stamp
+	rateLimit     *RateLimit
+}
+
+// MemoryEvent is a usage event recorded by a MemoryStore.
+type MemoryEvent struct {
+	AthleteId int64
+	Event     string
+	Time      time.Time
+}
+
+type memoryJob struct {
+	Job
+	status      string
+	runAt       time.Time
+	lockedUntil time.Time
+	lastError   string
+	requeued    bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		subscribers:   map[int64]Subscriber{},
+		settings:      map[int64]Settings{},
+		webhookEvents: map[WebhookEvent]bool{},
+		backfills:     map[int64]Backfill{},
+		stamps:        map[int64]Stamp{},
+	}
+}
+
+// Events returns the usage events recorded so far.
+func (m *MemoryStore) Events() []MemoryEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MemoryEvent(nil), m.events...)
+}
+
+// Jobs returns all jobs along with their status.
+func (m *MemoryStore) Jobs() map[Job]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := map[Job]string{}
+	for _, j := range m.jobs {
+		jobs[j.Job] = j.status
+	}
+	return jobs
+}
+
+func (m *MemoryStore) SaveSubscriber(ctx context.Context, s Subscriber) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers[s.AthleteId] = s
+	return nil
+}
+
+func (m *MemoryStore) GetSubscriber(ctx context.Context, athleteId int64) (Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subscribers[athleteId]
+	if !ok {
+		return Subscriber{}, sql.ErrNoRows
+	}
+	return s, nil
+}
+
+func (m *MemoryStore) SetSubscriberActive(ctx context.Context, athleteId int64, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.subscribers[athleteId]; ok {
+		s.Active = active
+		m.subscribers[athleteId] = s
+	}
+	return nil
+}
+
+func (m *MemoryStore) SubscribersNotSealedWith(ctx context.Context, keyId string) ([]Subscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var subs []Subscriber
+	for _, s := range m.subscribers {
+		if s.KeyId != keyId {
+			subs = append(subs, s)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].AthleteId < subs[j].AthleteId })
+	return subs, nil
+}
+
+func (m *MemoryStore) ResealSubscriber(ctx context.Context, old Subscriber, new Subscriber) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.subscribers[old.AthleteId]
+	if !ok || s.KeyId != old.KeyId || s.DataKey != old.DataKey || s.AccessToken != old.AccessToken || s.RefreshToken != old.RefreshToken {
+		return false, nil
+	}
+	s.AccessToken, s.RefreshToken, s.KeyId, s.DataKey = new.AccessToken, new.RefreshToken, new.KeyId, new.DataKey
+	m.subscribers[old.AthleteId] = s
+	return true, nil
+}
+
+func (m *MemoryStore) DeleteSubscriber(ctx context.Context, athleteId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.subscribers, athleteId)
+	return nil
+}
+
+func (m *MemoryStore) SaveSettings(ctx context.Context, athleteId int64, settings Settings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	settings.ActivityTypes = append([]string(nil), settings.ActivityTypes...)
+	settings.Destination = settings.destination()
+	m.settings[athleteId] = settings
+	return nil
+}
+
+func (m *MemoryStore) GetSettings(ctx context.Context, athleteId int64) (Settings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	settings, ok := m.settings[athleteId]
+	if !ok {
+		return Settings{}, sql.ErrNoRows
+	}
+	return settings, nil
+}
+
+func (m *MemoryStore) DeleteSettings(ctx context.Context, athleteId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.settings, athleteId)
+	return nil
+}
+
+func (m *MemoryStore) AddEvent(ctx context.Context, athleteId int64, event string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, MemoryEvent{AthleteId: athleteId, Event: event, Time: time.Now()})
+	return nil
+}
+
+func (m *MemoryStore) RecordWebhookEvent(ctx context.Context, event WebhookEvent, jobKind string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.webhookEvents[event] {
+		return false, nil
+	}
+	m.webhookEvents[event] = true
+	if jobKind != "" {
+		m.enqueueJob(jobKind, event.OwnerId, event.ObjectId, time.Now())
+	}
+	return true, nil
+}
+
+func (m *MemoryStore) DeleteActivity(ctx context.Context, athleteId int64, activityId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := m.jobs[:0]
+	for _, j := range m.jobs {
+		if !(j.AthleteId == athleteId && j.ObjectId == activityId && j.status == JobPending) {
+			jobs = append(jobs, j)
+		}
+	}
+	m.jobs = jobs
+	if stamp, ok := m.stamps[activityId]; ok && stamp.AthleteId == athleteId {
+		delete(m.stamps, activityId)
+	}
+	return nil
+}
+
+func (m *MemoryStore) EnqueueJob(ctx context.Context, kind string, athleteId int64, objectId int64, runAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enqueueJob(kind, athleteId, objectId, runAt)
+	return nil
+}
+
+func (m *MemoryStore) enqueueJob(kind string, athleteId int64, objectId int64, runAt time.Time) {
+	for _, j := range m.jobs {
+		if j.Kind == kind && j.AthleteId == athleteId && j.ObjectId == objectId && j.status == JobPending {
+			j.requeued = j.requeued || j.lockedUntil.After(time.Now())
+			return
+		}
+	}
+	m.nextJobId++
+	job := Job{Id: m.nextJobId, Kind: kind, AthleteId: athleteId, ObjectId: objectId, MaxAttempts: MaxAttempts}
+	m.jobs = append(m.jobs, &memoryJob{Job: job, status: JobPending, runAt: runAt})
+}
+
+func (m *MemoryStore) ClaimJob(ctx context.Context, now time.Time, lockedUntil time.Time) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var next *memoryJob
+	for _, j := range m.jobs {
+		if j.status != JobPending || j.runAt.After(now) || j.lockedUntil.After(now) {
+			continue
+		}
+		if next == nil || j.runAt.Before(next.runAt) {
+			next = j
+		}
+	}
+	if next == nil {
+		return Job{}, sql.ErrNoRows
+	}
+	next.Attempts++
+	next.lockedUntil = lockedUntil
+	return next.Job, nil
+}
+
+func (m *MemoryStore) job(id int64) *memoryJob {
+	for _, j := range m.jobs {
+		if j.Id == id {
+			return j
+		}
+	}
+	return &memoryJob{}
+}
+
+func (m *MemoryStore) CompleteJob(ctx context.Context, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.job(id)
+	j.status, j.lockedUntil, j.lastError = JobDone, time.Time{}, ""
+	if j.requeued {
+		j.status, j.Attempts, j.requeued = JobPending, 0, false
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeferJob(ctx context.Context, id int64, runAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.job(id)
+	j.Attempts--
+	j.runAt, j.lockedUntil, j.requeued = runAt, time.Time{}, false
+	return nil
+}
+
+func (m *MemoryStore) FailJob(ctx context.Context, id int64, status string, runAt time.Time, lastError string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.job(id)
+	j.status, j.runAt, j.lockedUntil, j.lastError, j.requeued = status, runAt, time.Time{}, lastError, false
+	return nil
+}
+
+func (m *MemoryStore) DeletePendingJobs(ctx context.Context, athleteId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := m.jobs[:0]
+	for _, j := range m.jobs {
+		if !(j.AthleteId == athleteId && j.status == JobPending) {
+			jobs = append(jobs, j)
+		}
+	}
+	m.jobs = jobs
+	return nil
+}
+
+func (m *MemoryStore) StartBackfill(ctx context.Context, b Backfill) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backfills[b.AthleteId] = b
+	return nil
+}
+
+func (m *MemoryStore) GetBackfill(ctx context.Context, athleteId int64) (Backfill, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.backfills[athleteId]
+	if !ok {
+		return Backfill{}, sql.ErrNoRows
+	}
+	return b, nil
+}
+
+func (m *MemoryStore) SaveBackfill(ctx context.Context, b Backfill) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.backfills[b.AthleteId]; ok {
+		old.Checkpoint, old.Processed, old.Status = b.Checkpoint, b.Processed, b.Status
+		m.backfills[b.AthleteId] = old
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteBackfill(ctx context.Context, athleteId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.backfills, athleteId)
+	return nil
+}
+
+func (m *MemoryStore) SaveStamp(ctx context.Context, stamp Stamp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.stamps[stamp.ActivityId]; ok {
+		stamp.AthleteId = old.AthleteId
+	}
+	m.stamps[stamp.ActivityId] = stamp
+	return nil
+}
+
+func (m *MemoryStore) GetStamps(ctx context.Context, athleteId int64) ([]Stamp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stamps []Stamp
+	for _, stamp := range m.stamps {
+		if stamp.AthleteId == athleteId && stamp.Text != "" {
+			stamps = append(stamps, stamp)
+		}
+	}
+	sort.Slice(stamps, func(i, j int) bool { return stamps[i].ActivityId < stamps[j].ActivityId })
+	return stamps, nil
+}
+
+func (m *MemoryStore) IsStamped(ctx context.Context, activityId int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.stamps[activityId]
+	return ok, nil
+}
+
+func (m *MemoryStore) DeleteStamp(ctx context.Context, activityId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.stamps, activityId)
+	return nil
+}
+
+func (m *MemoryStore) DeleteStamps(ctx context.Context, athleteId int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, stamp := range m.stamps {
+		if stamp.AthleteId == athleteId {
+			delete(m.stamps, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetRateLimit(ctx context.Context) (RateLimit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rateLimit == nil {
+		return RateLimit{}, sql.ErrNoRows
+	}
+	return *m.rateLimit, nil
+}
+
+func (m *MemoryStore) RecordRateLimit(ctx context.Context, rateLimit RateLimit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rateLimit == nil || !rateLimit.UpdatedAt.Before(m.rateLimit.UpdatedAt) {
+		m.rateLimit = &rateLimit
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"windspeed/utils/database"
+)
+
+// jobsOf returns the status of each job by kind and object id.
+func jobsOf(store *MemoryStore) map[string]string {
+	jobs := map[string]string{}
+	for job, status := range store.Jobs() {
+		jobs[fmt.Sprintf("%v %v/%v", job.Kind, job.AthleteId, job.ObjectId)] = status
+	}
+	return jobs
+}
+
+func TestRecordWebhookEvent(t *testing.T) {
+	store := NewMemoryStore()
+	created := WebhookEvent{OwnerId: 7, ObjectId: 5, AspectType: "create", EventTime: 1000}
+	updated := WebhookEvent{OwnerId: 7, ObjectId: 5, AspectType: "update", EventTime: 1060}
+	deleted := WebhookEvent{OwnerId: 7, ObjectId: 5, AspectType: "delete", EventTime: 1200}
+
+	tests := []struct {
+		name    string
+		event   WebhookEvent
+		jobKind string
+		isNew   bool
+		jobs    map[string]string
+	}{
+		{"created", created, JobAddWeather, true, map[string]string{"add_weather 7/5": JobPending}},
+		{"redelivered", created, JobAddWeather, false, map[string]string{"add_weather 7/5": JobPending}},
+		{"updated while pending", updated, JobAddWeather, true, map[string]string{"add_weather 7/5": JobPending}},
+		{"deleted", deleted, "", true, map[string]string{"add_weather 7/5": JobPending}},
+	}
+	for _, test := range tests {
+		isNew, err := RecordWebhookEvent(store, test.event, test.jobKind)
+		if err != nil || isNew != test.isNew {
+			t.Errorf("%v: RecordWebhookEvent = %v, %v, want %v", test.name, isNew, err, test.isNew)
+		}
+		if jobs := jobsOf(store); !reflect.DeepEqual(jobs, test.jobs) {
+			t.Errorf("%v: jobs = %v, want %v", test.name, jobs, test.jobs)
+		}
+	}
+
+	// Deleting the activity drops its pending job but keeps its events, so
+	// late redeliveries are still recognized and don't queue it again.
+	DeleteActivity(store, 7, 5)
+	if jobs := jobsOf(store); len(jobs) != 0 {
+		t.Errorf("jobs were not deleted: %v", jobs)
+	}
+	for _, event := range []WebhookEvent{created, deleted} {
+		if isNew, _ := RecordWebhookEvent(store, event, JobAddWeather); isNew {
+			t.Errorf("redelivered %v event was recorded as new", event.AspectType)
+		}
+	}
+	if jobs := jobsOf(store); len(jobs) != 0 {
+		t.Errorf("redelivery queued jobs: %v", jobs)
+	}
+}
+
+func TestProcessJobsRetries(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	if err := EnqueueJob(store, "unknown", 7, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := ProcessJobs(ctx, store, 10); n != 1 {
+		t.Errorf("ProcessJobs ran %v jobs, want 1", n)
+	}
+	if jobs, want := jobsOf(store), map[string]string{"unknown 7/5": JobPending}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+	if _, err := store.ClaimJob(ctx, time.Now(), time.Now().Add(VisibilityTimeout)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("failed job was not backed off: %v", err)
+	}
+
+	// The last attempt dead-letters the job.
+	later := time.Now().Add(backoff(MaxAttempts))
+	for i := 1; i < MaxAttempts; i++ {
+		job, err := store.ClaimJob(ctx, later, later.Add(VisibilityTimeout))
+		if err != nil {
+			t.Fatalf("failed to claim job: %v", err)
+		}
+		failJob(ctx, store, job, errors.New("failed again"))
+	}
+	if jobs, want := jobsOf(store), map[string]string{"unknown 7/5": JobDead}; !reflect.DeepEqual(jobs, want) {
+		t.Errorf("jobs = %v, want %v", jobs, want)
+	}
+}
+
+func TestProcessJobsDeadline(t *testing.T) {
+	store := NewMemoryStore()
+	if err := EnqueueJob(store, "unknown", 7, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// A job is not started unless it can run for JobTimeout.
+	ctx, cancel := context.WithTimeout(context.Background(), JobTimeout/2)
+	defer cancel()
+	if n := ProcessJobs(ctx, store, 10); n != 0 {
+		t.Errorf("ProcessJobs ran %v jobs, want 0", n)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 2*JobTimeout)
+	defer cancel()
+	if n := ProcessJobs(ctx, store, 10); n != 1 {
+		t.Errorf("ProcessJobs ran %v jobs, want 1", n)
+	}
+}
+
+func TestUserTokens(t *testing.T) {
+	refreshed := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth/token" || r.FormValue("refresh_token") != "refresh" {
+			http.Error(w, `{"message":"Bad Request"}`, http.StatusBadRequest)
+			return
+		}
+		refreshed++
+		fmt.Fprintf(w, `{"access_token":"new-access","refresh_token":"new-refresh","expires_at":%d}`, time.Now().Add(6*time.Hour).Unix())
+	}))
+	defer srv.Close()
+	api := API
+	API = NewClient(srv.Client(), srv.URL, "client-id", "client-secret")
+	defer func() { API = api }()
+
+	store := NewMemoryStore()
+	ctx := context.Background()
+	AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+	AddNewUser(store, 8, "access", "refresh", time.Now().Add(time.Minute).Unix())
+
+	tokens, err := userTokens(ctx, store, 7)
+	if err != nil || tokens.AccessToken != "access" || refreshed != 0 {
+		t.Errorf("userTokens = %+v, %v after %v refreshes, want the stored tokens", tokens, err, refreshed)
+	}
+	tokens, err = userTokens(ctx, store, 8)
+	if err != nil || tokens.AccessToken != "new-access" || refreshed != 1 {
+		t.Errorf("userTokens = %+v, %v after %v refreshes, want refreshed tokens", tokens, err, refreshed)
+	}
+	if tokens, _ := userTokens(ctx, store, 8); tokens.RefreshToken != "new-refresh" || refreshed != 1 {
+		t.Errorf("refreshed tokens were not stored: %+v", tokens)
+	}
+
+	deactivateUser(ctx, store, 7)
+	if _, err := userTokens(ctx, store, 7); !errors.Is(err, ErrRevoked) {
+		t.Errorf("userTokens of an inactive user = %v, want ErrRevoked", err)
+	}
+	if _, err := userTokens(ctx, store, 9); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("userTokens of an unknown user = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Hour).Unix())
+	AddUserSettings(store, 7, Settings{StampTemplate: "{{.temp}}"})
+	if err := store.StartBackfill(ctx, Backfill{AthleteId: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnqueueJob(store, JobBackfill, 7, 0); err != nil {
+		t.Fatal(err)
+	}
+	deactivateUser(ctx, store, 7)
+
+	DeleteUser(store, 7)
+	if _, err := store.GetSubscriber(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("subscriber was not deleted: %v", err)
+	}
+	if _, err := store.GetSettings(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("settings were not deleted: %v", err)
+	}
+	if _, err := store.GetBackfill(ctx, 7); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("backfill was not deleted: %v", err)
+	}
+	if jobs := jobsOf(store); len(jobs) != 0 {
+		t.Errorf("jobs were not deleted: %v", jobs)
+	}
+
+	var events []string
+	for _, event := range store.Events() {
+		events = append(events, event.Event)
+	}
+	if want := []string{database.NewUser, database.DeleteUser}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestResealSubscriber(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	old := Subscriber{AthleteId: 7, AccessToken: "access", RefreshToken: "refresh", ExpiresAt: 1000, Active: true}
+	refreshed := old
+	refreshed.AccessToken, refreshed.RefreshToken = "new-access", "new-refresh"
+	resealed := old
+	resealed.AccessToken, resealed.RefreshToken, resealed.KeyId, resealed.DataKey = "sealed-access", "sealed-refresh", "a", "data-key"
+
+	// The tokens were refreshed after old was read, under the same key.
+	if err := store.SaveSubscriber(ctx, refreshed); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.ResealSubscriber(ctx, old, resealed); ok || err != nil {
+		t.Errorf("ResealSubscriber over refreshed tokens = %v, %v, want false", ok, err)
+	}
+	if sub, _ := store.GetSubscriber(ctx, 7); sub != refreshed {
+		t.Errorf("subscriber = %+v, want the refreshed tokens", sub)
+	}
+	if ok, err := store.ResealSubscriber(ctx, refreshed, resealed); !ok || err != nil {
+		t.Errorf("ResealSubscriber = %v, %v, want true", ok, err)
+	}
+}
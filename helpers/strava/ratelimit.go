@@ -0,0 +1,164 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitReserve is the share of each budget left unused, so that work
+// is deferred shortly before Strava would start answering with 429s.
+const RateLimitReserve float64 = 0.1
+
+// RateLimit is the app wide usage Strava reports with every response.
+// The short budget resets every 15 minutes, the daily one at midnight UTC.
+// Reads count against both the overall and the smaller read budget.
+type RateLimit struct {
+	ShortLimit     int
+	ShortUsage     int
+	DailyLimit     int
+	DailyUsage     int
+	ReadShortLimit int
+	ReadShortUsage int
+	ReadDailyLimit int
+	ReadDailyUsage int
+	UpdatedAt      time.Time
+}
+
+// RateLimitError is returned instead of calling Strava once a budget is
+// nearly spent.
+type RateLimitError struct {
+	Until time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("strava rate limit nearly reached, waiting until %v", e.Until.Format(time.RFC3339))
+}
+
+// RateLimiter keeps track of the usage across all running functions.
+type RateLimiter interface {
+	Check(now time.Time) error
+	Record(rateLimit RateLimit)
+}
+
+// parseRateLimit reads the X-RateLimit-Limit and X-RateLimit-Usage headers,
+// each holding the 15 minute and daily values, e.g. "200,2000". The
+// X-ReadRateLimit-* headers of the read budget are optional.
+func parseRateLimit(h http.Header, now time.Time) (RateLimit, bool) {
+	values, ok := parseBudget(h, "X-RateLimit")
+	if !ok {
+		return RateLimit{}, false
+	}
+	rateLimit := RateLimit{
+		ShortLimit: values[0],
+		ShortUsage: values[1],
+		DailyLimit: values[2],
+		DailyUsage: values[3],
+		UpdatedAt:  now,
+	}
+	if values, ok := parseBudget(h, "X-ReadRateLimit"); ok {
+		rateLimit.ReadShortLimit = values[0]
+		rateLimit.ReadShortUsage = values[1]
+		rateLimit.ReadDailyLimit = values[2]
+		rateLimit.ReadDailyUsage = values[3]
+	}
+	return rateLimit, true
+}
+
+// parseBudget returns the short limit and usage, then the daily limit and
+// usage, from the prefix-Limit and prefix-Usage headers.
+func parseBudget(h http.Header, prefix string) ([]int, bool) {
+	limit := strings.Split(h.Get(prefix+"-Limit"), ",")
+	usage := strings.Split(h.Get(prefix+"-Usage"), ",")
+	if len(limit) < 2 || len(usage) < 2 {
+		return nil, false
+	}
+
+	values := make([]int, 4)
+	for i, s := range []string{limit[0], usage[0], limit[1], usage[1]} {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, false
+		}
+		values[i] = v
+	}
+	return values, true
+}
+
+// ExhaustedUntil reports when work may resume, if any budget is nearly
+// spent. Usage recorded in an earlier window no longer counts.
+func (r RateLimit) ExhaustedUntil(now time.Time) (time.Time, bool) {
+	return r.exhaustedUntil(now, 1-RateLimitReserve)
+}
+
+// exhaustedUntil is ExhaustedUntil for work allowed to use only the given
+// share of each budget.
+func (r RateLimit) exhaustedUntil(now time.Time, share float64) (time.Time, bool) {
+	nextShort := r.UpdatedAt.UTC().Truncate(15 * time.Minute).Add(15 * time.Minute)
+	nextDaily := r.UpdatedAt.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+	if now.Before(nextDaily) && (nearlySpent(r.DailyUsage, r.DailyLimit, share) || nearlySpent(r.ReadDailyUsage, r.ReadDailyLimit, share)) {
+		return nextDaily, true
+	}
+	if now.Before(nextShort) && (nearlySpent(r.ShortUsage, r.ShortLimit, share) || nearlySpent(r.ReadShortUsage, r.ReadShortLimit, share)) {
+		return nextShort, true
+	}
+	return time.Time{}, false
+}
+
+func nearlySpent(usage int, limit int, share float64) bool {
+	return limit > 0 && float64(usage) >= float64(limit)*share
+}
+
+// StoreRateLimiter shares the latest reported usage through the store,
+// e.g. the database all functions use. Calls are let through if it can't be
+// reached.
+type StoreRateLimiter struct {
+	Store Store
+}
+
+func (l StoreRateLimiter) Check(now time.Time) error {
+	rateLimit, err := l.Store.GetRateLimit(context.Background())
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("> failed to read strava rate limit: %v\n", err)
+		}
+		return nil
+	}
+	if until, ok := rateLimit.ExhaustedUntil(now); ok {
+		return &RateLimitError{Until: until}
+	}
+	return nil
+}
+
+func (l StoreRateLimiter) Record(rateLimit RateLimit) {
+	if err := l.Store.RecordRateLimit(context.Background(), rateLimit); err != nil {
+		log.Printf("> failed to record strava rate limit: %v\n", err)
+	}
+}
+
+// ShareRateLimit makes API check and record the rate limit through the
+// store. It is called by each function once it has opened its store.
+func ShareRateLimit(store Store) {
+	API.Limiter = StoreRateLimiter{Store: store}
+}
+
+// rateLimited turns rate limit errors into a *DeferredError, so the worker
+// retries the job once the budget is available again instead of failing it.
+func rateLimited(err error) error {
+	var limitErr *RateLimitError
+	if errors.As(err, &limitErr) {
+		return &DeferredError{Until: limitErr.Until, Err: err}
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return &DeferredError{Until: time.Now().UTC().Truncate(15 * time.Minute).Add(15 * time.Minute), Err: err}
+	}
+	return err
+}
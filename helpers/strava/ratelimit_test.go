@@ -0,0 +1,126 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    RateLimit
+		ok      bool
+	}{
+		{"missing", nil, RateLimit{}, false},
+		{
+			"overall",
+			map[string]string{"X-RateLimit-Limit": "200,2000", "X-RateLimit-Usage": "12,345"},
+			RateLimit{ShortLimit: 200, ShortUsage: 12, DailyLimit: 2000, DailyUsage: 345, UpdatedAt: now},
+			true,
+		},
+		{
+			"with spaces",
+			map[string]string{"X-RateLimit-Limit": "200, 2000", "X-RateLimit-Usage": " 12 , 345"},
+			RateLimit{ShortLimit: 200, ShortUsage: 12, DailyLimit: 2000, DailyUsage: 345, UpdatedAt: now},
+			true,
+		},
+		{
+			"with reads",
+			map[string]string{
+				"X-RateLimit-Limit": "200,2000", "X-RateLimit-Usage": "12,345",
+				"X-ReadRateLimit-Limit": "100,1000", "X-ReadRateLimit-Usage": "10,300",
+			},
+			RateLimit{ShortLimit: 200, ShortUsage: 12, DailyLimit: 2000, DailyUsage: 345,
+				ReadShortLimit: 100, ReadShortUsage: 10, ReadDailyLimit: 1000, ReadDailyUsage: 300, UpdatedAt: now},
+			true,
+		},
+		{
+			"malformed reads",
+			map[string]string{
+				"X-RateLimit-Limit": "200,2000", "X-RateLimit-Usage": "12,345",
+				"X-ReadRateLimit-Limit": "100", "X-ReadRateLimit-Usage": "10,300",
+			},
+			RateLimit{ShortLimit: 200, ShortUsage: 12, DailyLimit: 2000, DailyUsage: 345, UpdatedAt: now},
+			true,
+		},
+		{"reads only", map[string]string{"X-ReadRateLimit-Limit": "100,1000", "X-ReadRateLimit-Usage": "10,300"}, RateLimit{}, false},
+		{"single value", map[string]string{"X-RateLimit-Limit": "200", "X-RateLimit-Usage": "12"}, RateLimit{}, false},
+		{"not a number", map[string]string{"X-RateLimit-Limit": "200,lots", "X-RateLimit-Usage": "12,345"}, RateLimit{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := http.Header{}
+			for name, value := range test.headers {
+				h.Set(name, value)
+			}
+			got, ok := parseRateLimit(h, now)
+			if got != test.want || ok != test.ok {
+				t.Errorf("parseRateLimit = %+v, %v, want %+v, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestExhaustedUntil(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 10, 20, 0, 0, time.UTC)
+	nextShort := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	nextDaily := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		rateLimit RateLimit
+		now       time.Time
+		until     time.Time
+		exhausted bool
+	}{
+		{"unknown limits", RateLimit{UpdatedAt: updatedAt}, updatedAt, time.Time{}, false},
+		{"plenty left", RateLimit{ShortLimit: 200, ShortUsage: 100, DailyLimit: 2000, DailyUsage: 1000, UpdatedAt: updatedAt}, updatedAt, time.Time{}, false},
+		{"short spent", RateLimit{ShortLimit: 200, ShortUsage: 180, DailyLimit: 2000, DailyUsage: 1000, UpdatedAt: updatedAt}, updatedAt, nextShort, true},
+		{"short spent last window", RateLimit{ShortLimit: 200, ShortUsage: 200, DailyLimit: 2000, UpdatedAt: updatedAt}, nextShort, time.Time{}, false},
+		{"daily spent", RateLimit{ShortLimit: 200, ShortUsage: 200, DailyLimit: 2000, DailyUsage: 1900, UpdatedAt: updatedAt}, updatedAt, nextDaily, true},
+		{"daily spent yesterday", RateLimit{ShortLimit: 200, DailyLimit: 2000, DailyUsage: 2000, UpdatedAt: updatedAt}, nextDaily, time.Time{}, false},
+		{"short reads spent", RateLimit{ShortLimit: 200, ShortUsage: 90, ReadShortLimit: 100, ReadShortUsage: 90, UpdatedAt: updatedAt}, updatedAt, nextShort, true},
+		{"daily reads spent", RateLimit{DailyLimit: 2000, DailyUsage: 950, ReadDailyLimit: 1000, ReadDailyUsage: 950, UpdatedAt: updatedAt}, updatedAt, nextDaily, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			until, exhausted := test.rateLimit.ExhaustedUntil(test.now)
+			if !until.Equal(test.until) || exhausted != test.exhausted {
+				t.Errorf("ExhaustedUntil = %v, %v, want %v, %v", until, exhausted, test.until, test.exhausted)
+			}
+		})
+	}
+}
+
+func TestStoreRateLimiter(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-RateLimit-Limit", "200,2000")
+		w.Header().Set("X-RateLimit-Usage", "190,300")
+		fmt.Fprint(w, `{"id":5}`)
+	}))
+	defer srv.Close()
+
+	store := NewMemoryStore()
+	client := NewClient(srv.Client(), srv.URL, "client-id", "client-secret")
+	client.Limiter = StoreRateLimiter{Store: store}
+	ctx := context.Background()
+
+	if _, err := client.GetActivity(ctx, "access", 5); err != nil {
+		t.Fatalf("GetActivity without a recorded rate limit = %v", err)
+	}
+	if rateLimit, err := store.GetRateLimit(ctx); err != nil || rateLimit.ShortUsage != 190 {
+		t.Errorf("recorded rate limit = %+v, %v, want a short usage of 190", rateLimit, err)
+	}
+	var limitErr *RateLimitError
+	if _, err := client.GetActivity(ctx, "access", 5); !errors.As(err, &limitErr) || calls != 1 {
+		t.Errorf("GetActivity = %v after %v calls, want a *RateLimitError without calling strava", err, calls)
+	}
+}
@@ -0,0 +1,351 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"windspeed/utils/database"
+	"windspeed/utils/weather"
+)
+
+// SQLStore keeps the data in a SQL database. Queries name tables as
+// {table}, which the dialect of the database turns into a table name.
+type SQLStore struct {
+	DB     *sql.DB
+	tables *strings.Replacer
+}
+
+// storeTables are the tables of the strava schema.
+var storeTables = []string{"subscribers", "settings", "webhook_events", "jobs", "backfills", "stamps", "rate_limits"}
+
+// NewPostgresStore uses the strava and events schemas of DB_DATABASE.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	names := []string{"{events}", fmt.Sprintf("%s.events.events", os.Getenv("DB_DATABASE")), "{skip_locked}", "FOR UPDATE SKIP LOCKED"}
+	for _, table := range storeTables {
+		names = append(names, "{"+table+"}", fmt.Sprintf("%s.%s.%s", os.Getenv("DB_DATABASE"), DB_SCHEMA, table))
+	}
+	return &SQLStore{DB: db, tables: strings.NewReplacer(names...)}
+}
+
+// NewSQLiteStore uses a single file database, which has no schemas, so the
+// tables are prefixed with the schema name instead. There is only one
+// writer at a time, so rows don't need to be locked.
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	names := []string{"{events}", "events", "{skip_locked}", ""}
+	for _, table := range storeTables {
+		names = append(names, "{"+table+"}", DB_SCHEMA+"_"+table)
+	}
+	return &SQLStore{DB: db, tables: strings.NewReplacer(names...)}
+}
+
+func (s *SQLStore) query(sql string) string {
+	return s.tables.Replace(sql)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQLStore) SaveSubscriber(ctx context.Context, sub Subscriber) error {
+	sql := `INSERT INTO {subscribers} (id, access_token, refresh_token, expires_at, key_id, data_key, active) VALUES($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (id) DO UPDATE SET access_token = $2, refresh_token = $3, expires_at = $4, key_id = $5, data_key = $6, active = $7;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), sub.AthleteId, sub.AccessToken, sub.RefreshToken, sub.ExpiresAt, sub.KeyId, sub.DataKey, sub.Active)
+	return err
+}
+
+func (s *SQLStore) GetSubscriber(ctx context.Context, athleteId int64) (Subscriber, error) {
+	sql := `SELECT id, access_token, refresh_token, expires_at, key_id, data_key, active FROM {subscribers} WHERE id = $1 LIMIT 1`
+	var sub Subscriber
+	err := s.DB.QueryRowContext(ctx, s.query(sql), athleteId).
+		Scan(&sub.AthleteId, &sub.AccessToken, &sub.RefreshToken, &sub.ExpiresAt, &sub.KeyId, &sub.DataKey, &sub.Active)
+	return sub, err
+}
+
+func (s *SQLStore) SetSubscriberActive(ctx context.Context, athleteId int64, active bool) error {
+	sql := `UPDATE {subscribers} SET active = $2 WHERE id = $1;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId, active)
+	return err
+}
+
+func (s *SQLStore) SubscribersNotSealedWith(ctx context.Context, keyId string) ([]Subscriber, error) {
+	sql := `SELECT id, access_token, refresh_token, expires_at, key_id, data_key, active FROM {subscribers} WHERE key_id <> $1 ORDER BY id`
+	rows, err := s.DB.QueryContext(ctx, s.query(sql), keyId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []Subscriber
+	for rows.Next() {
+		var sub Subscriber
+		if err := rows.Scan(&sub.AthleteId, &sub.AccessToken, &sub.RefreshToken, &sub.ExpiresAt, &sub.KeyId, &sub.DataKey, &sub.Active); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *SQLStore) ResealSubscriber(ctx context.Context, old Subscriber, new Subscriber) (bool, error) {
+	sql := `UPDATE {subscribers} SET access_token = $2, refresh_token = $3, key_id = $4, data_key = $5
+	WHERE id = $1 AND key_id = $6 AND data_key = $7 AND access_token = $8 AND refresh_token = $9;`
+	result, err := s.DB.ExecContext(ctx, s.query(sql), old.AthleteId, new.AccessToken, new.RefreshToken, new.KeyId, new.DataKey, old.KeyId, old.DataKey, old.AccessToken, old.RefreshToken)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+func (s *SQLStore) DeleteSubscriber(ctx context.Context, athleteId int64) error {
+	sql := `DELETE FROM {subscribers} WHERE id = $1`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId)
+	return err
+}
+
+// SaveSettings also stores the unit system, for rolling back to a version
+// that predates split units.
+func (s *SQLStore) SaveSettings(ctx context.Context, athleteId int64, settings Settings) error {
+	sql := `INSERT INTO {settings} (id, units, temperature_unit, wind_unit, pressure_unit, stamp_template, activity_types, destination) VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (id) DO UPDATE SET units = $2, temperature_unit = $3, wind_unit = $4, pressure_unit = $5, stamp_template = $6, activity_types = $7, destination = $8;`
+	units := settings.Units
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId, units.System(), units.Temperature, units.Wind, units.Pressure, settings.StampTemplate, strings.Join(settings.ActivityTypes, ","), settings.destination())
+	return err
+}
+
+func (s *SQLStore) GetSettings(ctx context.Context, athleteId int64) (Settings, error) {
+	sql := `SELECT units, temperature_unit, wind_unit, pressure_unit, stamp_template, activity_types, destination FROM {settings} WHERE id = $1`
+	var system, activityTypes string
+	var settings Settings
+	err := s.DB.QueryRowContext(ctx, s.query(sql), athleteId).
+		Scan(&system, &settings.Units.Temperature, &settings.Units.Wind, &settings.Units.Pressure, &settings.StampTemplate, &activityTypes, &settings.Destination)
+	if err != nil {
+		return Settings{}, err
+	}
+	if activityTypes != "" {
+		settings.ActivityTypes = strings.Split(activityTypes, ",")
+	}
+
+	// users who subscribed before units were split only have a unit system
+	if units, err := weather.ParseUnits(settings.Units.Temperature, settings.Units.Wind, settings.Units.Pressure); err == nil {
+		settings.Units = units
+	} else {
+		settings.Units = weather.UnitsFromSystem(system)
+	}
+	return settings, nil
+}
+
+func (s *SQLStore) DeleteSettings(ctx context.Context, athleteId int64) error {
+	sql := `DELETE FROM {settings} WHERE id = $1`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId)
+	return err
+}
+
+func (s *SQLStore) AddEvent(ctx context.Context, athleteId int64, event string) error {
+	sql := `INSERT INTO {events} (event_time, anonymous_id, service, event) VALUES($1, $2, $3, $4);`
+	anonymousId := database.CreateAnonymousId(fmt.Sprintf("%d", athleteId), "strava")
+	_, err := s.DB.ExecContext(ctx, s.query(sql), time.Now().Unix(), anonymousId, "strava", event)
+	return err
+}
+
+func (s *SQLStore) RecordWebhookEvent(ctx context.Context, event WebhookEvent, jobKind string) (bool, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	sql := `INSERT INTO {webhook_events} (owner_id, object_id, aspect_type, event_time, received_at) VALUES($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING;`
+	result, err := tx.ExecContext(ctx, s.query(sql), event.OwnerId, event.ObjectId, event.AspectType, event.EventTime, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if jobKind != "" {
+		if err := s.enqueueJob(ctx, tx, jobKind, event.OwnerId, event.ObjectId, time.Now()); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func (s *SQLStore) DeleteActivity(ctx context.Context, athleteId int64, activityId int64) error {
+	queries := []string{
+		`DELETE FROM {jobs} WHERE athlete_id = $1 AND object_id = $2 AND status = 'pending'`,
+		`DELETE FROM {stamps} WHERE athlete_id = $1 AND activity_id = $2`,
+	}
+	for _, sql := range queries {
+		if _, err := s.DB.ExecContext(ctx, s.query(sql), athleteId, activityId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) EnqueueJob(ctx context.Context, kind string, athleteId int64, objectId int64, runAt time.Time) error {
+	return s.enqueueJob(ctx, s.DB, kind, athleteId, objectId, runAt)
+}
+
+// enqueueJob marks a pending job for the same object that is running right
+// now as requeued, so it runs once more after completing.
+func (s *SQLStore) enqueueJob(ctx context.Context, db execer, kind string, athleteId int64, objectId int64, runAt time.Time) error {
+	sql := `INSERT INTO {jobs} AS j (kind, athlete_id, object_id, max_attempts, run_at, created_at) VALUES($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (kind, athlete_id, object_id) WHERE status = 'pending' DO UPDATE SET requeued = j.requeued OR j.locked_until > $6;`
+	_, err := db.ExecContext(ctx, s.query(sql), kind, athleteId, objectId, MaxAttempts, runAt.Unix(), time.Now().Unix())
+	return err
+}
+
+func (s *SQLStore) ClaimJob(ctx context.Context, now time.Time, lockedUntil time.Time) (Job, error) {
+	sql := `UPDATE {jobs} SET attempts = attempts + 1, locked_until = $1
+	WHERE id = (
+		SELECT id FROM {jobs}
+		WHERE status = $2 AND run_at <= $3 AND locked_until <= $3
+		ORDER BY run_at
+		LIMIT 1
+		{skip_locked})
+	RETURNING id, kind, athlete_id, object_id, attempts, max_attempts;`
+	var job Job
+	err := s.DB.QueryRowContext(ctx, s.query(sql), lockedUntil.Unix(), JobPending, now.Unix()).
+		Scan(&job.Id, &job.Kind, &job.AthleteId, &job.ObjectId, &job.Attempts, &job.MaxAttempts)
+	return job, err
+}
+
+func (s *SQLStore) CompleteJob(ctx context.Context, id int64) error {
+	sql := `UPDATE {jobs} SET status = CASE WHEN requeued THEN $3 ELSE $2 END, attempts = CASE WHEN requeued THEN 0 ELSE attempts END,
+	requeued = false, locked_until = 0, last_error = '' WHERE id = $1;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), id, JobDone, JobPending)
+	return err
+}
+
+func (s *SQLStore) DeferJob(ctx context.Context, id int64, runAt time.Time) error {
+	sql := `UPDATE {jobs} SET attempts = attempts - 1, run_at = $2, locked_until = 0, requeued = false WHERE id = $1;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), id, runAt.Unix())
+	return err
+}
+
+func (s *SQLStore) FailJob(ctx context.Context, id int64, status string, runAt time.Time, lastError string) error {
+	sql := `UPDATE {jobs} SET status = $2, run_at = $3, locked_until = 0, last_error = $4, requeued = false WHERE id = $1;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), id, status, runAt.Unix(), lastError)
+	return err
+}
+
+func (s *SQLStore) DeletePendingJobs(ctx context.Context, athleteId int64) error {
+	sql := `DELETE FROM {jobs} WHERE athlete_id = $1 AND status = $2`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId, JobPending)
+	return err
+}
+
+func (s *SQLStore) StartBackfill(ctx context.Context, b Backfill) error {
+	sql := `INSERT INTO {backfills} (athlete_id, "after", "before", checkpoint, processed, status, updated_at) VALUES($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (athlete_id) DO UPDATE SET "after" = $2, "before" = $3, checkpoint = $4, processed = $5, status = $6, updated_at = $7;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), b.AthleteId, b.After, b.Before, b.Checkpoint, b.Processed, b.Status, time.Now().Unix())
+	return err
+}
+
+func (s *SQLStore) GetBackfill(ctx context.Context, athleteId int64) (Backfill, error) {
+	sql := `SELECT athlete_id, "after", "before", checkpoint, processed, status FROM {backfills} WHERE athlete_id = $1`
+	var b Backfill
+	err := s.DB.QueryRowContext(ctx, s.query(sql), athleteId).
+		Scan(&b.AthleteId, &b.After, &b.Before, &b.Checkpoint, &b.Processed, &b.Status)
+	return b, err
+}
+
+func (s *SQLStore) SaveBackfill(ctx context.Context, b Backfill) error {
+	sql := `UPDATE {backfills} SET checkpoint = $2, processed = $3, status = $4, updated_at = $5 WHERE athlete_id = $1;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), b.AthleteId, b.Checkpoint, b.Processed, b.Status, time.Now().Unix())
+	return err
+}
+
+func (s *SQLStore) DeleteBackfill(ctx context.Context, athleteId int64) error {
+	sql := `DELETE FROM {backfills} WHERE athlete_id = $1`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId)
+	return err
+}
+
+func (s *SQLStore) SaveStamp(ctx context.Context, stamp Stamp) error {
+	report, err := json.Marshal(stamp.Report)
+	if err != nil {
+		return err
+	}
+	sql := `INSERT INTO {stamps} (activity_id, athlete_id, destination, stamp, report, updated_at) VALUES($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (activity_id) DO UPDATE SET destination = $3, stamp = $4, report = $5, updated_at = $6;`
+	_, err = s.DB.ExecContext(ctx, s.query(sql), stamp.ActivityId, stamp.AthleteId, stamp.Destination, stamp.Text, string(report), time.Now().Unix())
+	return err
+}
+
+func (s *SQLStore) GetStamps(ctx context.Context, athleteId int64) ([]Stamp, error) {
+	sql := `SELECT activity_id, athlete_id, destination, stamp, report FROM {stamps} WHERE athlete_id = $1 AND stamp <> '' ORDER BY activity_id`
+	rows, err := s.DB.QueryContext(ctx, s.query(sql), athleteId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stamps []Stamp
+	for rows.Next() {
+		var stamp Stamp
+		var report string
+		if err := rows.Scan(&stamp.ActivityId, &stamp.AthleteId, &stamp.Destination, &stamp.Text, &report); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(report), &stamp.Report); err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps, rows.Err()
+}
+
+func (s *SQLStore) IsStamped(ctx context.Context, activityId int64) (bool, error) {
+	sql := `SELECT EXISTS (SELECT 1 FROM {stamps} WHERE activity_id = $1)`
+	var stamped bool
+	err := s.DB.QueryRowContext(ctx, s.query(sql), activityId).Scan(&stamped)
+	return stamped, err
+}
+
+func (s *SQLStore) DeleteStamp(ctx context.Context, activityId int64) error {
+	sql := `DELETE FROM {stamps} WHERE activity_id = $1`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), activityId)
+	return err
+}
+
+func (s *SQLStore) DeleteStamps(ctx context.Context, athleteId int64) error {
+	sql := `DELETE FROM {stamps} WHERE athlete_id = $1`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), athleteId)
+	return err
+}
+
+func (s *SQLStore) GetRateLimit(ctx context.Context) (RateLimit, error) {
+	sql := `SELECT short_limit, short_usage, daily_limit, daily_usage, read_short_limit, read_short_usage, read_daily_limit, read_daily_usage, updated_at
+	FROM {rate_limits} WHERE id = 1`
+	var r RateLimit
+	var updatedAt int64
+	err := s.DB.QueryRowContext(ctx, s.query(sql)).Scan(&r.ShortLimit, &r.ShortUsage, &r.DailyLimit, &r.DailyUsage,
+		&r.ReadShortLimit, &r.ReadShortUsage, &r.ReadDailyLimit, &r.ReadDailyUsage, &updatedAt)
+	r.UpdatedAt = time.Unix(updatedAt, 0)
+	return r, err
+}
+
+func (s *SQLStore) RecordRateLimit(ctx context.Context, rateLimit RateLimit) error {
+	sql := `INSERT INTO {rate_limits} (id, short_limit, short_usage, daily_limit, daily_usage, read_short_limit, read_short_usage, read_daily_limit, read_daily_usage, updated_at)
+	VALUES(1, $1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (id) DO UPDATE SET short_limit = $1, short_usage = $2, daily_limit = $3, daily_usage = $4,
+	read_short_limit = $5, read_short_usage = $6, read_daily_limit = $7, read_daily_usage = $8, updated_at = $9
+	WHERE {rate_limits}.updated_at <= $9;`
+	_, err := s.DB.ExecContext(ctx, s.query(sql), rateLimit.ShortLimit, rateLimit.ShortUsage, rateLimit.DailyLimit, rateLimit.DailyUsage,
+		rateLimit.ReadShortLimit, rateLimit.ReadShortUsage, rateLimit.ReadDailyLimit, rateLimit.ReadDailyUsage, rateLimit.UpdatedAt.Unix())
+	return err
+}
@@ -0,0 +1,114 @@
+package strava
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"windspeed/utils/database"
+	"windspeed/utils/migrations"
+)
+
+// sqliteStore returns a store on a freshly migrated SQLite database.
+func sqliteStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "windspeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db, database.SQLite); err != nil {
+		t.Fatalf("migrations failed: %v", err)
+	}
+	return NewSQLiteStore(db)
+}
+
+func TestSQLStoreClaimJob(t *testing.T) {
+	store := sqliteStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	if err := store.EnqueueJob(ctx, JobAddWeather, 7, 5, now); err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout))
+	if err != nil || job.Kind != JobAddWeather || job.AthleteId != 7 || job.ObjectId != 5 || job.Attempts != 1 {
+		t.Fatalf("ClaimJob = %+v, %v", job, err)
+	}
+	if _, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("claimed a running job: %v", err)
+	}
+
+	// An event arriving while the job runs requeues it rather than adding
+	// a second job, so it runs once more after completing.
+	if err := store.EnqueueJob(ctx, JobAddWeather, 7, 5, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CompleteJob(ctx, job.Id); err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout))
+	if err != nil || again.Id != job.Id || again.Attempts != 1 {
+		t.Fatalf("ClaimJob of the requeued job = %+v, %v", again, err)
+	}
+
+	if err := store.CompleteJob(ctx, again.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("claimed a completed job: %v", err)
+	}
+}
+
+func TestSQLStoreDeferJob(t *testing.T) {
+	store := sqliteStore(t)
+	ctx := context.Background()
+	now := time.Now()
+	if err := store.EnqueueJob(ctx, JobAddWeather, 7, 5, now); err != nil {
+		t.Fatal(err)
+	}
+	job, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	until := now.Add(time.Hour)
+	if err := store.DeferJob(ctx, job.Id, until); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ClaimJob(ctx, now, now.Add(VisibilityTimeout)); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("claimed a deferred job early: %v", err)
+	}
+	later, err := store.ClaimJob(ctx, until, until.Add(VisibilityTimeout))
+	if err != nil || later.Id != job.Id || later.Attempts != 1 {
+		t.Errorf("ClaimJob after the deferral = %+v, %v, want the job on its first attempt", later, err)
+	}
+}
+
+func TestSQLStoreRecordRateLimit(t *testing.T) {
+	store := sqliteStore(t)
+	ctx := context.Background()
+	if _, err := store.GetRateLimit(ctx); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRateLimit before any request = %v, want sql.ErrNoRows", err)
+	}
+
+	now := time.Unix(time.Now().Unix(), 0)
+	first := RateLimit{ShortLimit: 200, ShortUsage: 10, DailyLimit: 2000, DailyUsage: 100, UpdatedAt: now}
+	latest := RateLimit{ShortLimit: 200, ShortUsage: 12, DailyLimit: 2000, DailyUsage: 102,
+		ReadShortLimit: 100, ReadShortUsage: 4, ReadDailyLimit: 1000, ReadDailyUsage: 40, UpdatedAt: now.Add(time.Second)}
+	stale := RateLimit{ShortLimit: 200, ShortUsage: 11, DailyLimit: 2000, DailyUsage: 101, UpdatedAt: now}
+
+	// Responses may be recorded out of order, so an older one must not
+	// overwrite the usage of a newer one.
+	for _, r := range []RateLimit{first, latest, stale} {
+		if err := store.RecordRateLimit(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, err := store.GetRateLimit(ctx); err != nil || got != latest {
+		t.Errorf("GetRateLimit = %+v, %v, want %+v", got, err, latest)
+	}
+}
@@ -0,0 +1,178 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"windspeed/utils/weather"
+)
+
+// Stamp is the exact text added to an activity, along with the weather it
+// was rendered from. The text is empty once the user removed the stamp.
+type Stamp struct {
+	ActivityId  int64
+	AthleteId   int64
+	Destination string
+	Text        string
+	Report      weather.Report
+}
+
+// RestampActivities renders each of the users stamps again with their
+// current settings, and rewrites those that changed, moving them if the
+// user picked another destination. It reports whether all
+// stamps are up to date; otherwise it can be resumed by calling it again.
+func RestampActivities(ctx context.Context, store Store, athleteId int64, deadline time.Time) (bool, error) {
+	log.Printf("> re-rendering stamps for strava user: %v\n", athleteId)
+	settings := getUserSettings(ctx, store, athleteId)
+	stamps, err := store.GetStamps(ctx, athleteId)
+	if err != nil {
+		return false, err
+	}
+	tokens, err := userTokens(ctx, store, athleteId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, stamp := range stamps {
+		text, err := settings.renderStamp(stamp.Report)
+		if err != nil || (text == stamp.Text && settings.destination() == stamp.Destination) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if err := backfillPause(ctx, store); err != nil {
+			return false, err
+		}
+		if err := rewriteStamp(ctx, store, tokens, stamp, text, settings.destination()); err != nil {
+			var deferred *DeferredError
+			if err := rateLimited(unauthorized(ctx, store, tokens, err)); errors.As(err, &deferred) || errors.Is(err, ErrRevoked) {
+				return false, err
+			}
+			if isTransient(err) {
+				return false, &DeferredError{Until: time.Now().Add(BackfillRetryDelay), Err: err}
+			}
+			// Give up on this stamp, so the next run doesn't start with it
+			// again. Like a stamp the user removed, the record is kept with
+			// an empty text so the activity is not stamped again.
+			log.Printf("> failed to re-render stamp of activity %v, skipping it: %v\n", stamp.ActivityId, err)
+			stamp.Text = ""
+			if err := store.SaveStamp(ctx, stamp); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// removeStamps strips the users stamps from their activities. It reports
+// whether all of them are gone; otherwise it can be resumed by calling it
+// again. Stamps that can't be removed are given up on.
+func removeStamps(ctx context.Context, store Store, athleteId int64, deadline time.Time) (bool, error) {
+	stamps, err := store.GetStamps(ctx, athleteId)
+	if err != nil {
+		return false, err
+	}
+	var stamped []Stamp
+	for _, stamp := range stamps {
+		if stamp.Text != "" {
+			stamped = append(stamped, stamp)
+		}
+	}
+	if len(stamped) == 0 {
+		return true, nil
+	}
+	tokens, err := userTokens(ctx, store, athleteId)
+	if err != nil {
+		return false, err
+	}
+
+	log.Printf("> removing %v stamps for strava user: %v\n", len(stamped), athleteId)
+	for _, stamp := range stamped {
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		if err := backfillPause(ctx, store); err != nil {
+			return false, err
+		}
+		if err := rewriteStamp(ctx, store, tokens, stamp, "", stamp.Destination); err != nil {
+			var deferred *DeferredError
+			if err := rateLimited(unauthorized(ctx, store, tokens, err)); errors.As(err, &deferred) || errors.Is(err, ErrRevoked) {
+				return false, err
+			}
+			if isTransient(err) {
+				return false, &DeferredError{Until: time.Now().Add(BackfillRetryDelay), Err: err}
+			}
+			log.Printf("> failed to remove stamp of activity %v, skipping it: %v\n", stamp.ActivityId, err)
+			if err := store.DeleteStamp(ctx, stamp.ActivityId); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// rewriteStamp replaces the stamp with text at the given destination, or
+// removes it if text is empty. Stamps the user has edited or removed
+// themselves are left alone.
+func rewriteStamp(ctx context.Context, store Store, tokens Tokens, stamp Stamp, text string, destination string) error {
+	activity, err := API.GetActivity(ctx, tokens.AccessToken, stamp.ActivityId)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return store.DeleteStamp(ctx, stamp.ActivityId)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Take the old stamp out, or replace it in place if it stays put.
+	oldField := activity.field(stamp.Destination)
+	replacement := ""
+	if destination == stamp.Destination {
+		replacement = text
+	}
+	updated, ok := replaceStamp(*oldField, stamp.Text, replacement)
+	if !ok {
+		// Keep the record with an empty text, so the activity is not stamped again.
+		log.Printf("> stamp no longer found in activity %v\n", stamp.ActivityId)
+		stamp.Text = ""
+		return store.SaveStamp(ctx, stamp)
+	}
+	*oldField = updated
+	destinations := []string{stamp.Destination}
+
+	// Add the new stamp to its new destination.
+	if text != "" && destination != stamp.Destination {
+		newField := activity.field(destination)
+		*newField = appendStamp(*newField, text, destination)
+		destinations = append(destinations, destination)
+	}
+	if err := modifyActivity(ctx, activity, tokens, destinations...); err != nil {
+		return err
+	}
+
+	if text == "" {
+		return store.DeleteStamp(ctx, stamp.ActivityId)
+	}
+	stamp.Text = text
+	stamp.Destination = destination
+	return store.SaveStamp(ctx, stamp)
+}
+
+// replaceStamp replaces the last occurrence of old in the description. When
+// removing a stamp, the line break separating it from the users own text is
+// removed as well.
+func replaceStamp(description string, old string, new string) (string, bool) {
+	i := strings.LastIndex(description, old)
+	if i < 0 {
+		return description, false
+	}
+	if new == "" {
+		return strings.TrimRight(description[:i], "\n ") + description[i+len(old):], true
+	}
+	return description[:i] + new + description[i+len(old):], true
+}
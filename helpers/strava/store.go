@@ -0,0 +1,106 @@
+package strava
+
+import (
+	"context"
+	"time"
+
+	"windspeed/utils/database"
+	"windspeed/utils/secrets"
+)
+
+// Store persists everything windspeed.app knows about its Strava users.
+// Lookups of missing records return sql.ErrNoRows, whatever the backend.
+type Store interface {
+	// Subscribers, with their tokens as stored, see sealTokens.
+	SaveSubscriber(ctx context.Context, s Subscriber) error
+	GetSubscriber(ctx context.Context, athleteId int64) (Subscriber, error)
+	SetSubscriberActive(ctx context.Context, athleteId int64, active bool) error
+	// SubscribersNotSealedWith lists the subscribers whose tokens are not
+	// sealed with the given key.
+	SubscribersNotSealedWith(ctx context.Context, keyId string) ([]Subscriber, error)
+	// ResealSubscriber replaces the tokens of old with those of new, unless
+	// they have changed in the meantime, and reports whether it did.
+	ResealSubscriber(ctx context.Context, old Subscriber, new Subscriber) (bool, error)
+	DeleteSubscriber(ctx context.Context, athleteId int64) error
+
+	SaveSettings(ctx context.Context, athleteId int64, settings Settings) error
+	GetSettings(ctx context.Context, athleteId int64) (Settings, error)
+	DeleteSettings(ctx context.Context, athleteId int64) error
+
+	// AddEvent records an anonymous usage event, keyed by
+	// database.CreateAnonymousId.
+	AddEvent(ctx context.Context, athleteId int64, event string) error
+
+	// RecordWebhookEvent stores the event and reports whether it is new. If
+	// jobKind is not empty, a job for the events object is enqueued along
+	// with it, so an event is never recorded without its job.
+	RecordWebhookEvent(ctx context.Context, event WebhookEvent, jobKind string) (bool, error)
+	// DeleteActivity removes the pending jobs and stamp of an activity. Its
+	// webhook events are kept, so redeliveries are still recognized.
+	DeleteActivity(ctx context.Context, athleteId int64, activityId int64) error
+
+	// EnqueueJob adds a pending job, unless one exists for the same object.
+	// If that one is running, it is run once more after completing.
+	EnqueueJob(ctx context.Context, kind string, athleteId int64, objectId int64, runAt time.Time) error
+	// ClaimJob takes the oldest due job, counting the attempt and hiding it
+	// from other workers until lockedUntil.
+	ClaimJob(ctx context.Context, now time.Time, lockedUntil time.Time) (Job, error)
+	// CompleteJob marks the job done, or pending again if it was requeued.
+	CompleteJob(ctx context.Context, id int64) error
+	// DeferJob puts the job back without using up one of its attempts.
+	DeferJob(ctx context.Context, id int64, runAt time.Time) error
+	FailJob(ctx context.Context, id int64, status string, runAt time.Time, lastError string) error
+	// DeletePendingJobs removes the pending jobs of a user, including those
+	// currently running.
+	DeletePendingJobs(ctx context.Context, athleteId int64) error
+
+	// StartBackfill creates the backfill or resets it to b.
+	StartBackfill(ctx context.Context, b Backfill) error
+	GetBackfill(ctx context.Context, athleteId int64) (Backfill, error)
+	// SaveBackfill stores the checkpoint, progress and status of b.
+	SaveBackfill(ctx context.Context, b Backfill) error
+	DeleteBackfill(ctx context.Context, athleteId int64) error
+
+	SaveStamp(ctx context.Context, stamp Stamp) error
+	// GetStamps returns the users stamps, leaving out those they removed.
+	GetStamps(ctx context.Context, athleteId int64) ([]Stamp, error)
+	// IsStamped reports whether the activity has ever been stamped.
+	IsStamped(ctx context.Context, activityId int64) (bool, error)
+	DeleteStamp(ctx context.Context, activityId int64) error
+	DeleteStamps(ctx context.Context, athleteId int64) error
+
+	GetRateLimit(ctx context.Context) (RateLimit, error)
+	// RecordRateLimit stores the usage, unless a more recent one is stored.
+	RecordRateLimit(ctx context.Context, rateLimit RateLimit) error
+}
+
+// Subscriber is a user as stored. The tokens are encrypted if KeyId is set.
+type Subscriber struct {
+	AthleteId    int64
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    int64
+	KeyId        string
+	DataKey      string
+	Active       bool
+}
+
+func (s Subscriber) sealed() secrets.Sealed {
+	return secrets.Sealed{KeyId: s.KeyId, DataKey: s.DataKey, Values: []string{s.AccessToken, s.RefreshToken}}
+}
+
+// OpenStore returns the store used by the helpers. It
+// can be replaced, e.g. with one returning a MemoryStore.
+var OpenStore func(ctx context.Context) (Store, error) = openDatabaseStore
+
+// openDatabaseStore uses the shared database pool, see database.Driver.
+func openDatabaseStore(ctx context.Context) (Store, error) {
+	db, err := database.Pool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if database.Driver() == database.SQLite {
+		return NewSQLiteStore(db), nil
+	}
+	return NewPostgresStore(db), nil
+}
@@ -0,0 +1,332 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"windspeed/utils/secrets"
+)
+
+// TokenRefreshMargin is how long before they expire tokens are refreshed, so
+// they don't run out halfway through a job.
+const TokenRefreshMargin time.Duration = 5 * time.Minute
+
+// TokenRefreshAttempts bounds the attempts made to refresh tokens when Strava
+// fails with a transient error.
+const TokenRefreshAttempts int = 3
+
+// ClientAuthRetryDelay is how long work waits after Strava rejected the
+// client credentials, giving the operator time to fix them.
+const ClientAuthRetryDelay time.Duration = 15 * time.Minute
+
+// tokenRetryDelay is the wait before retrying a refresh, doubled each time.
+var tokenRetryDelay = time.Second
+
+// tokenKeys returns the keyring sealing the tokens stored in
+// strava.subscribers, see secrets.FromEnv. Without keys every read and write
+// of tokens fails, rather than storing them as plain text.
+func tokenKeys() (*secrets.Keyring, error) {
+	return secrets.FromEnv()
+}
+
+func init() {
+	if _, err := tokenKeys(); err != nil {
+		log.Printf("> WARNING: strava tokens can't be stored or read: %v\n", err)
+	}
+}
+
+// ErrRevoked is returned when the athlete has revoked windspeed's access to
+// their account. The user is marked inactive and won't be retried.
+var ErrRevoked = errors.New("strava authorization has been revoked")
+
+// valid reports whether the tokens are complete enough to be stored.
+func (t Tokens) valid() bool {
+	return t.AthleteId != 0 && t.AccessToken != "" && t.RefreshToken != "" && t.ExpiresAt > 0
+}
+
+// expiresWithin reports whether the access token expires in less than margin.
+func (t Tokens) expiresWithin(margin time.Duration, now time.Time) bool {
+	return t.ExpiresAt-int64(margin.Seconds()) <= now.Unix()
+}
+
+// updateUserTokens encrypts and stores the tokens, marking the user active
+// again. Partial tokens are refused so a failed refresh can never wipe
+// working ones.
+func updateUserTokens(ctx context.Context, store Store, tokens Tokens) error {
+	if !tokens.valid() {
+		return fmt.Errorf("refusing to store incomplete tokens for strava user \"%v\"", tokens.AthleteId)
+	}
+	sealed, err := sealTokens(tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt tokens for strava user \"%v\": %w", tokens.AthleteId, err)
+	}
+
+	sub := Subscriber{
+		AthleteId:    tokens.AthleteId,
+		AccessToken:  sealed.Values[0],
+		RefreshToken: sealed.Values[1],
+		ExpiresAt:    tokens.ExpiresAt,
+		KeyId:        sealed.KeyId,
+		DataKey:      sealed.DataKey,
+		Active:       true,
+	}
+	if err := store.SaveSubscriber(ctx, sub); err != nil {
+		return fmt.Errorf("failed to update tokens for strava user \"%v\": %w", tokens.AthleteId, err)
+	}
+	log.Printf("> successfully updated tokens for strava user \"%v\"\n", tokens.AthleteId)
+	return nil
+}
+
+// getUserTokens returns the stored tokens of the user and whether they are
+// still active.
+func getUserTokens(ctx context.Context, store Store, athleteId int64) (Tokens, bool, error) {
+	log.Printf("> getting user tokens\n")
+	sub, err := store.GetSubscriber(ctx, athleteId)
+	if err != nil {
+		return Tokens{}, false, fmt.Errorf("error getting tokens of strava user %v: %w", athleteId, err)
+	}
+	tokens, err := openTokens(Tokens{AthleteId: sub.AthleteId, ExpiresAt: sub.ExpiresAt}, sub.sealed())
+	if err != nil {
+		return Tokens{}, false, fmt.Errorf("error decrypting tokens of strava user %v: %w", athleteId, err)
+	}
+	return tokens, sub.Active, nil
+}
+
+// sealTokens encrypts the access and refresh tokens, bound to the athlete id.
+func sealTokens(tokens Tokens) (secrets.Sealed, error) {
+	keys, err := tokenKeys()
+	if err != nil {
+		return secrets.Sealed{}, err
+	}
+	return keys.Seal([]byte(fmt.Sprintf("%d", tokens.AthleteId)), tokens.AccessToken, tokens.RefreshToken)
+}
+
+// openTokens decrypts the access and refresh tokens into tokens. Rows stored
+// before encryption was enabled have no key id and are read as they are.
+func openTokens(tokens Tokens, sealed secrets.Sealed) (Tokens, error) {
+	keys, err := tokenKeys()
+	if err != nil {
+		return Tokens{}, err
+	}
+	values, err := keys.Open([]byte(fmt.Sprintf("%d", tokens.AthleteId)), sealed)
+	if err != nil {
+		return Tokens{}, err
+	}
+	tokens.AccessToken, tokens.RefreshToken = values[0], values[1]
+	return tokens, nil
+}
+
+// RotateTokenKeys re-encrypts the tokens of every user not yet sealed with
+// the current key, including plain text ones, and returns how many were
+// rotated. Rows updated concurrently are left to the writer.
+func RotateTokenKeys(store Store) (int, error) {
+	keys, err := tokenKeys()
+	if err != nil {
+		return 0, err
+	}
+	current := keys.CurrentKeyId()
+	if current == "" {
+		return 0, errors.New("no token key configured, set TOKEN_KEYS")
+	}
+
+	ctx := context.Background()
+	subs, err := store.SubscribersNotSealedWith(ctx, current)
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, sub := range subs {
+		tokens, err := openTokens(Tokens{AthleteId: sub.AthleteId, ExpiresAt: sub.ExpiresAt}, sub.sealed())
+		if err != nil {
+			return rotated, fmt.Errorf("error decrypting tokens of strava user %v: %w", sub.AthleteId, err)
+		}
+		sealed, err := sealTokens(tokens)
+		if err != nil {
+			return rotated, err
+		}
+
+		resealed := sub
+		resealed.AccessToken, resealed.RefreshToken = sealed.Values[0], sealed.Values[1]
+		resealed.KeyId, resealed.DataKey = sealed.KeyId, sealed.DataKey
+		ok, err := store.ResealSubscriber(ctx, sub, resealed)
+		if err != nil {
+			return rotated, err
+		}
+		if ok {
+			rotated++
+		}
+	}
+	return rotated, nil
+}
+
+// deactivateUser marks the user as having revoked their authorization, so no
+// further work is attempted on their behalf.
+func deactivateUser(ctx context.Context, store Store, athleteId int64) {
+	log.Printf("> marking strava user %v inactive\n", athleteId)
+	if err := store.SetSubscriberActive(ctx, athleteId, false); err != nil {
+		log.Printf("> failed to mark strava user %v inactive: %v\n", athleteId, err)
+	}
+}
+
+// userTokens returns valid tokens for the user, refreshing and persisting
+// them if they are about to expire.
+func userTokens(ctx context.Context, store Store, athleteId int64) (Tokens, error) {
+	tokens, active, err := getUserTokens(ctx, store, athleteId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !active {
+		return Tokens{}, fmt.Errorf("strava user %v: %w", athleteId, ErrRevoked)
+	}
+	if !tokens.expiresWithin(TokenRefreshMargin, time.Now()) {
+		log.Printf("> current user tokens are still valid\n")
+		return tokens, nil
+	}
+	return renewUserTokens(ctx, store, tokens)
+}
+
+// renewUserTokens refreshes the tokens and persists them, marking the user
+// inactive if Strava reports the authorization as revoked.
+func renewUserTokens(ctx context.Context, store Store, tokens Tokens) (Tokens, error) {
+	refreshed, err := refreshUserTokens(ctx, tokens)
+	if errors.Is(err, ErrRevoked) {
+		deactivateUser(ctx, store, tokens.AthleteId)
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// Strava may have rotated the refresh token, so losing these would lock
+	// the user out. They are still good for this run though.
+	log.Printf("> persisting updated tokens\n")
+	if err := updateUserTokens(ctx, store, refreshed); err != nil {
+		log.Printf("> %v\n", err)
+	}
+	return refreshed, nil
+}
+
+// unauthorized checks an error returned by the Strava API. If the access
+// token was rejected before it expired, the tokens are renewed so a retry
+// can succeed, which also detects a revoked authorization.
+func unauthorized(ctx context.Context, store Store, tokens Tokens, err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+	log.Printf("> access token of strava user %v was rejected\n", tokens.AthleteId)
+	if _, renewErr := renewUserTokens(ctx, store, tokens); renewErr != nil {
+		return renewErr
+	}
+	return err
+}
+
+// refreshUserTokens exchanges the refresh token for new tokens, retrying
+// transient failures with backoff.
+func refreshUserTokens(ctx context.Context, tokens Tokens) (Tokens, error) {
+	log.Printf("> contacting strava for latest user tokens\n")
+	delay := tokenRetryDelay
+	for attempt := 1; ; attempt++ {
+		refreshed, err := API.RefreshToken(ctx, tokens.RefreshToken)
+		if err == nil {
+			refreshed.AthleteId = tokens.AthleteId
+			if !refreshed.valid() {
+				return Tokens{}, errors.New("strava returned incomplete tokens")
+			}
+			log.Printf("> tokens have been refreshed\n")
+			return refreshed, nil
+		}
+		if isRevoked(err) {
+			return Tokens{}, fmt.Errorf("%w: %v", ErrRevoked, err)
+		}
+		if isClientAuth(err) {
+			log.Printf("> strava rejected the client credentials, check STRAVA_CLIENT_ID and STRAVA_CLIENT_SECRET: %v\n", err)
+			return Tokens{}, &DeferredError{Until: time.Now().Add(ClientAuthRetryDelay), Err: fmt.Errorf("error refreshing user tokens from strava: %w", err)}
+		}
+		if !isTransient(err) || attempt >= TokenRefreshAttempts {
+			return Tokens{}, fmt.Errorf("error refreshing user tokens from strava: %w", err)
+		}
+
+		log.Printf("> refreshing tokens failed (attempt %v of %v), retrying in %v: %v\n", attempt, TokenRefreshAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return Tokens{}, fmt.Errorf("error refreshing user tokens from strava: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// tokenFault is the body of a failed token request. Strava lists the
+// offending fields, OAuth style errors name the failure in Error.
+type tokenFault struct {
+	Error  string `json:"error"`
+	Errors []struct {
+		Resource string `json:"resource"`
+		Field    string `json:"field"`
+		Code     string `json:"code"`
+	} `json:"errors"`
+}
+
+// isRevoked reports whether Strava refused the refresh token itself, because
+// the athlete revoked access. Any other failure leaves the user active.
+func isRevoked(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || isClientAuth(err) {
+		return false
+	}
+	var fault tokenFault
+	if json.Unmarshal([]byte(apiErr.Body), &fault) != nil {
+		return false
+	}
+	if fault.Error == "invalid_grant" {
+		return true
+	}
+	for _, e := range fault.Errors {
+		if e.Field == "refresh_token" && e.Code == "invalid" {
+			return true
+		}
+	}
+	return false
+}
+
+// isClientAuth reports whether Strava rejected windspeed's own client id or
+// secret. That is a configuration error, not something the athlete did.
+func isClientAuth(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	var fault tokenFault
+	if apiErr.StatusCode != http.StatusBadRequest || json.Unmarshal([]byte(apiErr.Body), &fault) != nil {
+		return false
+	}
+	if fault.Error == "invalid_client" {
+		return true
+	}
+	for _, e := range fault.Errors {
+		if e.Field == "client_id" || e.Field == "client_secret" || e.Resource == "Application" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient reports whether a failed request is worth retrying.
+func isTransient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
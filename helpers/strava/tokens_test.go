@@ -0,0 +1,56 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefreshFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		revoked  bool
+		deferred bool
+	}{
+		{"revoked grant", http.StatusBadRequest, `{"error":"invalid_grant"}`, true, false},
+		{"invalid refresh token", http.StatusBadRequest, `{"message":"Bad Request","errors":[{"resource":"RefreshToken","field":"refresh_token","code":"invalid"}]}`, true, false},
+		{"bad client secret", http.StatusUnauthorized, `{"message":"Authorization Error","errors":[{"resource":"Application","field":"client_secret","code":"invalid"}]}`, false, true},
+		{"bad client id", http.StatusBadRequest, `{"message":"Bad Request","errors":[{"resource":"Application","field":"client_id","code":"invalid"}]}`, false, true},
+		{"other bad request", http.StatusBadRequest, `{"message":"Bad Request"}`, false, false},
+		{"server error", http.StatusInternalServerError, `{"message":"Error"}`, false, false},
+	}
+	delay := tokenRetryDelay
+	tokenRetryDelay = time.Millisecond
+	defer func() { tokenRetryDelay = delay }()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, test.body, test.status)
+			}))
+			defer srv.Close()
+			api := API
+			API = NewClient(srv.Client(), srv.URL, "client-id", "client-secret")
+			defer func() { API = api }()
+
+			store := NewMemoryStore()
+			ctx := context.Background()
+			AddNewUser(store, 7, "access", "refresh", time.Now().Add(time.Minute).Unix())
+
+			_, err := userTokens(ctx, store, 7)
+			var deferred *DeferredError
+			if err == nil || errors.Is(err, ErrRevoked) != test.revoked || errors.As(err, &deferred) != test.deferred {
+				t.Errorf("userTokens = %v, want revoked %v, deferred %v", err, test.revoked, test.deferred)
+			}
+			sub, err := store.GetSubscriber(ctx, 7)
+			if err != nil || sub.Active == test.revoked {
+				t.Errorf("subscriber active = %v, %v, want %v", sub.Active, err, !test.revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package strava
+
+import (
+	"context"
+	"log"
+)
+
+// WebhookEvent identifies a single delivery from Stravas webhook. Strava
+// retries deliveries, so the same event can arrive more than once.
+type WebhookEvent struct {
+	OwnerId    int64
+	ObjectId   int64
+	AspectType string
+	EventTime  int64
+}
+
+// RecordWebhookEvent stores the event and reports whether it is new. If
+// jobKind is not empty, a job for the events object is enqueued along with
+// it, so an event is never recorded without its job.
+func RecordWebhookEvent(store Store, event WebhookEvent, jobKind string) (bool, error) {
+	isNew, err := store.RecordWebhookEvent(context.Background(), event, jobKind)
+	if err == nil && !isNew {
+		log.Printf("> duplicate webhook event for strava user: %v, object = %v\n", event.OwnerId, event.ObjectId)
+	}
+	return isNew, err
+}
+
+// DeleteActivity removes what is stored about a deleted activity: its
+// pending jobs and stamp. The recorded webhook events are kept, so that
+// redeliveries are still recognized.
+func DeleteActivity(store Store, athleteId int64, activityId int64) {
+	log.Printf("> remove strava activity: %v for user: %v\n", activityId, athleteId)
+	if err := store.DeleteActivity(context.Background(), athleteId, activityId); err != nil {
+		log.Printf("db-err: %s\n", err)
+	}
+}
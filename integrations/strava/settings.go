@@ -0,0 +1,11 @@
+package main
+
+import (
+	"windspeed/handlers"
+
+	"github.com/aws/aws-lambda-go/lambda"
+)
+
+func main() {
+	lambda.Start(handlers.ManageSettings)
+}
@@ -0,0 +1,8 @@
+// Package templates holds the HTML pages, embedded so the handlers work
+// wherever the binary runs.
+package templates
+
+import "embed"
+
+//go:embed *.html
+var FS embed.FS
@@ -1,19 +1,66 @@
 package database
 
 import (
+    "context"
     "crypto/md5"
 	"database/sql"
     "fmt"
 	"log"
 	"os"
+    "strconv"
+    "sync"
     "time"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 const NewUser    string = `{"event_type":"user_subscribed"}`
 const DeleteUser string = `{"event_type":"user_unsubscribed"}`
 
+// Pool limits, overridable with the DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS,
+// DB_CONN_MAX_LIFETIME, DB_CONN_MAX_IDLE_TIME, DB_CONNECT_TIMEOUT and
+// DB_QUERY_TIMEOUT environment variables. Lambdas run one request at a
+// time, so a handful of connections is plenty.
+const (
+    DefaultMaxOpenConns    int           = 4
+    DefaultMaxIdleConns    int           = 2
+    DefaultConnMaxLifetime time.Duration = 30 * time.Minute
+    DefaultConnMaxIdleTime time.Duration = 5 * time.Minute
+    DefaultConnectTimeout  time.Duration = 5 * time.Second
+    DefaultQueryTimeout    time.Duration = 10 * time.Second
+)
+
+// HealthCheckInterval is how long the pool may sit unused before it is
+// pinged again, e.g. after a Lambda was frozen between invocations.
+const HealthCheckInterval time.Duration = 30 * time.Second
+
+// Database drivers, selected with DB_DRIVER. Postgres is the default; SQLite
+// keeps everything in the single file at DB_PATH, for self-hosting.
+const (
+    Postgres string = "postgres"
+    SQLite   string = "sqlite"
+)
+
+const DefaultSQLitePath string = "windspeed.db"
+
+var (
+    pool     *sql.DB
+    poolErr  error
+    poolOnce sync.Once
+
+    lastCheck   time.Time
+    lastCheckMu sync.Mutex
+)
+
+// Driver returns the configured database driver.
+func Driver() string {
+    if driver := os.Getenv("DB_DRIVER"); driver != "" {
+        return driver
+    }
+    return Postgres
+}
+
 func connectionString() string {
 	var url string = "postgresql://"
 	url += os.Getenv("DB_USER") + ":"
@@ -21,36 +68,87 @@ func connectionString() string {
 	url += os.Getenv("DB_HOST") + ":"
 	url += os.Getenv("DB_PORT") + "/"
 	url += os.Getenv("DB_DATABASE") + "?sslmode=verify-full"
+	url += fmt.Sprintf("&connect_timeout=%d", int(envDuration("DB_CONNECT_TIMEOUT", DefaultConnectTimeout).Seconds()))
+	url += fmt.Sprintf("&statement_timeout=%d", envDuration("DB_QUERY_TIMEOUT", DefaultQueryTimeout).Milliseconds())
 	return url
 }
 
-func Connect() *sql.DB {
-	db, err := sql.Open("postgres", connectionString())
-	if err != nil {
-		log.Fatal(err)
-	}
-	return db
-}
-
-func AddEvent(userId string, service string, event string, db *sql.DB) {
-    log.Printf("> adding event\n")
-    anonymousId := CreateAnonymousId(fmt.Sprintf("%v", userId), service)
-                                    
-    sql := `INSERT INTO %s.events.events (event_time, anonymous_id, service, event) VALUES($1, $2, $3, $4);`
-	stmt, err := db.Prepare(fmt.Sprintf(sql, os.Getenv("DB_DATABASE")))
-	if err != nil {
-		log.Printf("> failed to prepare insert: %v", err)
-	}
-	result, err := stmt.Exec(time.Now().Unix(), anonymousId, service, event)
-	if err != nil {
-		log.Printf("db-err: %s\n", err)
-	}
-    rowsAffected, err := result.RowsAffected()
-	if rowsAffected == 1 {
-		log.Printf("successfully added event for: %v\n", anonymousId)
-	} else {
-		log.Printf("failed to add event for: %v\n", anonymousId)
-	}
+// sqliteConnectionString waits for the lock rather than failing when another
+// connection is writing, and lets readers carry on while it does.
+func sqliteConnectionString() string {
+    path := os.Getenv("DB_PATH")
+    if path == "" {
+        path = DefaultSQLitePath
+    }
+    timeout := envDuration("DB_QUERY_TIMEOUT", DefaultQueryTimeout).Milliseconds()
+    return fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", path, timeout)
+}
+
+// Pool returns the shared connection pool, opening it on first use. It lives
+// as long as the process, so warm Lambda invocations reuse its connections;
+// callers must not close it. The pool is pinged if it has not been used for
+// HealthCheckInterval.
+func Pool(ctx context.Context) (*sql.DB, error) {
+    poolOnce.Do(func() {
+        switch Driver() {
+        case Postgres:
+            pool, poolErr = sql.Open("postgres", connectionString())
+        case SQLite:
+            pool, poolErr = sql.Open("sqlite", sqliteConnectionString())
+        default:
+            poolErr = fmt.Errorf("unsupported DB_DRIVER: %q", Driver())
+        }
+        if poolErr != nil {
+            return
+        }
+        pool.SetMaxOpenConns(envInt("DB_MAX_OPEN_CONNS", DefaultMaxOpenConns))
+        if Driver() == SQLite {
+            // SQLite has a single writer anyway.
+            pool.SetMaxOpenConns(1)
+        }
+        pool.SetMaxIdleConns(envInt("DB_MAX_IDLE_CONNS", DefaultMaxIdleConns))
+        pool.SetConnMaxLifetime(envDuration("DB_CONN_MAX_LIFETIME", DefaultConnMaxLifetime))
+        pool.SetConnMaxIdleTime(envDuration("DB_CONN_MAX_IDLE_TIME", DefaultConnMaxIdleTime))
+    })
+    if poolErr != nil {
+        return nil, poolErr
+    }
+
+    lastCheckMu.Lock()
+    defer lastCheckMu.Unlock()
+    if time.Since(lastCheck) < HealthCheckInterval {
+        return pool, nil
+    }
+    pingCtx, cancel := context.WithTimeout(ctx, envDuration("DB_CONNECT_TIMEOUT", DefaultConnectTimeout))
+    defer cancel()
+    if err := pool.PingContext(pingCtx); err != nil {
+        return nil, fmt.Errorf("database is unreachable: %w", err)
+    }
+    lastCheck = time.Now()
+    return pool, nil
+}
+
+// MustPool is Pool for commands, which have nothing to do without a database.
+func MustPool() *sql.DB {
+    db, err := Pool(context.Background())
+    if err != nil {
+        log.Fatal(err)
+    }
+    return db
+}
+
+func envInt(name string, fallback int) int {
+    if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+        return n
+    }
+    return fallback
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+    if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+        return d
+    }
+    return fallback
 }
 
 func CreateAnonymousId(userId string, service string) string {
@@ -0,0 +1,240 @@
+// Package migrations keeps the database schema up to date. Migrations are
+// numbered SQL files embedded in the binary, named
+// "<version>_<name>.up.sql" and "<version>_<name>.down.sql", with one
+// directory per database driver, and the applied versions are tracked in the
+// schema_migrations table.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"windspeed/utils/database"
+)
+
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql
+var files embed.FS
+
+// lockId serializes migrations run from several places at once.
+const lockId int64 = 7316642
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration and when it was applied, zero if it is pending.
+type Status struct {
+	Migration
+	AppliedAt int64
+}
+
+// Load returns the embedded migrations for the database driver, see
+// database.Driver, ordered by version.
+func Load(driver string) ([]Migration, error) {
+	if driver != database.Postgres && driver != database.SQLite {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+	return load(files, path.Join("sql", driver))
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := cutDirection(name)
+		if !ok {
+			return nil, fmt.Errorf("migration %q must end in .up.sql or .down.sql", name)
+		}
+		number, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %q must start with a version number", name)
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", m.Name, label, version)
+		}
+		if direction == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutDirection(name string) (string, string, bool) {
+	if base, ok := strings.CutSuffix(name, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok := strings.CutSuffix(name, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func Up(db *sql.DB, driver string) (int, error) {
+	migrations, err := Load(driver)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		log.Printf("> applying migration %d_%s\n", m.Version, m.Name)
+		record := `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);`
+		if err := run(db, driver, m.Version, true, m.Up, record, m.Version, m.Name, time.Now().Unix()); err != nil {
+			return count, fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the last steps applied migrations and returns how many
+// were rolled back.
+func Down(db *sql.DB, driver string, steps int) (int, error) {
+	statuses, err := Statuses(db, driver)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(statuses) - 1; i >= 0 && count < steps; i-- {
+		m := statuses[i]
+		if m.AppliedAt == 0 {
+			continue
+		}
+		if m.Down == "" {
+			return count, fmt.Errorf("migration %d_%s can't be rolled back", m.Version, m.Name)
+		}
+		log.Printf("> rolling back migration %d_%s\n", m.Version, m.Name)
+		record := `DELETE FROM schema_migrations WHERE version = $1;`
+		if err := run(db, driver, m.Version, false, m.Down, record, m.Version); err != nil {
+			return count, fmt.Errorf("rolling back migration %d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Statuses returns every known migration along with when it was applied.
+func Statuses(db *sql.DB, driver string) ([]Status, error) {
+	migrations, err := Load(driver)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = Status{Migration: m, AppliedAt: applied[m.Version]}
+	}
+	return statuses, nil
+}
+
+// run executes a migration and records it in the same transaction, holding
+// a lock so concurrent runs can't apply it twice. SQLite allows only one
+// writing transaction at a time anyway.
+func run(db *sql.DB, driver string, version int, up bool, script string, record string, args ...interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if driver == database.Postgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, lockId); err != nil {
+			return err
+		}
+	}
+	var applied bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1);`, version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied == up {
+		// Someone else got there first.
+		return nil
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// appliedVersions returns when each applied version was applied, creating
+// the schema_migrations table if needed.
+func appliedVersions(db *sql.DB) (map[int]int64, error) {
+	create := `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version       integer    NOT NULL PRIMARY KEY,
+    name          text       NOT NULL,
+    applied_at    int8       NOT NULL);`
+	if _, err := db.Exec(create); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]int64{}
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
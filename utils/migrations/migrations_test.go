@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"windspeed/utils/database"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "windspeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// applied returns the versions Statuses reports as applied.
+func applied(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	statuses, err := Statuses(db, database.SQLite)
+	if err != nil {
+		t.Fatalf("Statuses failed: %v", err)
+	}
+	var versions []int
+	for _, s := range statuses {
+		if s.AppliedAt != 0 {
+			versions = append(versions, s.Version)
+		}
+	}
+	return versions
+}
+
+// tables returns the number of tables besides schema_migrations and those
+// SQLite keeps for itself.
+func tables(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name <> 'schema_migrations' AND name NOT LIKE 'sqlite_%'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRoundTrip(t *testing.T) {
+	db := openSQLite(t)
+	migrations, err := Load(database.SQLite)
+	if err != nil || len(migrations) == 0 {
+		t.Fatalf("Load = %v, %v, want the sqlite migrations", migrations, err)
+	}
+	last := migrations[len(migrations)-1].Version
+
+	if n, err := Up(db, database.SQLite); err != nil || n != len(migrations) {
+		t.Fatalf("Up = %v, %v, want %v", n, err, len(migrations))
+	}
+	if versions := applied(t, db); len(versions) != len(migrations) {
+		t.Errorf("applied %v, want all %v migrations", versions, len(migrations))
+	}
+	if n, err := Up(db, database.SQLite); err != nil || n != 0 {
+		t.Errorf("Up again = %v, %v, want 0", n, err)
+	}
+
+	if n, err := Down(db, database.SQLite, 1); err != nil || n != 1 {
+		t.Fatalf("Down(1) = %v, %v, want 1", n, err)
+	}
+	if versions := applied(t, db); len(versions) != len(migrations)-1 || versions[len(versions)-1] == last {
+		t.Errorf("applied %v after Down(1), want all but %v", versions, last)
+	}
+	if n, err := Up(db, database.SQLite); err != nil || n != 1 {
+		t.Errorf("Up after Down(1) = %v, %v, want 1", n, err)
+	}
+
+	if n, err := Down(db, database.SQLite, len(migrations)+1); err != nil || n != len(migrations) {
+		t.Fatalf("Down(all) = %v, %v, want %v", n, err, len(migrations))
+	}
+	if versions := applied(t, db); len(versions) != 0 {
+		t.Errorf("applied %v after Down(all), want none", versions)
+	}
+	if n := tables(t, db); n != 0 {
+		t.Errorf("%v tables left after rolling back everything", n)
+	}
+	if n, err := Up(db, database.SQLite); err != nil || n != len(migrations) {
+		t.Errorf("Up after Down(all) = %v, %v, want %v", n, err, len(migrations))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, driver := range []string{database.Postgres, database.SQLite} {
+		migrations, err := Load(driver)
+		if err != nil {
+			t.Fatalf("Load(%v) failed: %v", driver, err)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 || m.Up == "" || m.Down == "" {
+				t.Errorf("%v migration %v_%v is out of order or incomplete", driver, m.Version, m.Name)
+			}
+		}
+	}
+	if _, err := Load("mysql"); err == nil {
+		t.Errorf("Load(mysql) succeeded, want an error")
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"no direction", fstest.MapFS{"m/0001_init.sql": {}}},
+		{"no version", fstest.MapFS{"m/init.up.sql": {Data: []byte("SELECT 1;")}}},
+		{"shared version", fstest.MapFS{"m/0001_init.up.sql": {Data: []byte("SELECT 1;")}, "m/0001_other.up.sql": {Data: []byte("SELECT 1;")}}},
+		{"down only", fstest.MapFS{"m/0001_init.down.sql": {Data: []byte("SELECT 1;")}}},
+	}
+	for _, test := range tests {
+		if _, err := load(test.files, "m"); err == nil {
+			t.Errorf("%v: load succeeded, want an error", test.name)
+		}
+	}
+}
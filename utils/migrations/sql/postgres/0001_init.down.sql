@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS events.events;
+DROP TABLE IF EXISTS strava.settings;
+DROP TABLE IF EXISTS strava.subscribers;
+DROP SCHEMA IF EXISTS strava;
+DROP SCHEMA IF EXISTS events;
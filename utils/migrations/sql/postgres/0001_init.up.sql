@@ -1,8 +1,8 @@
 -- schemas
-CREATE SCHEMA IF NOT EXISTS events
-CREATE SCHEMA IF NOT EXISTS strava
+CREATE SCHEMA IF NOT EXISTS events;
+CREATE SCHEMA IF NOT EXISTS strava;
 
--- subscribers    
+-- subscribers
 CREATE TABLE IF NOT EXISTS strava.subscribers (
     id            integer    NOT NULL PRIMARY KEY,
     access_token  text       NOT NULL,
@@ -16,7 +16,7 @@ CREATE TABLE IF NOT EXISTS strava.settings (
 
 -- events
 CREATE TABLE IF NOT EXISTS events.events (
-    event_time 	  int8      NOT NULL,
-    anonymous_id  text      NOT NULL,
-    service       text      NOT NULL,
-    event         text      NOT NULL);
+    event_time    int8       NOT NULL,
+    anonymous_id  text       NOT NULL,
+    service       text       NOT NULL,
+    event         text       NOT NULL);
@@ -0,0 +1,6 @@
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS destination;
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS activity_types;
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS pressure_unit;
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS wind_unit;
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS temperature_unit;
+ALTER TABLE strava.settings DROP COLUMN IF EXISTS stamp_template;
@@ -0,0 +1,7 @@
+-- stamp format, split units, activity types and destination
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS stamp_template text NOT NULL DEFAULT '';
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS temperature_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS wind_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS pressure_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS activity_types text NOT NULL DEFAULT '';
+ALTER TABLE strava.settings ADD COLUMN IF NOT EXISTS destination text NOT NULL DEFAULT 'description';
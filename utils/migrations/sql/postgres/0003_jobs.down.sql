@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS strava.backfills;
+DROP TABLE IF EXISTS strava.webhook_events;
+DROP TABLE IF EXISTS strava.jobs;
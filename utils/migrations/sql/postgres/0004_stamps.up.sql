@@ -0,0 +1,10 @@
+-- stamps, the exact text added to each activity
+CREATE TABLE IF NOT EXISTS strava.stamps (
+    activity_id   int8       NOT NULL PRIMARY KEY,
+    athlete_id    integer    NOT NULL,
+    destination   text       NOT NULL DEFAULT 'description',
+    stamp         text       NOT NULL,
+    report        text       NOT NULL,
+    updated_at    int8       NOT NULL);
+
+CREATE INDEX IF NOT EXISTS stamps_athlete ON strava.stamps (athlete_id);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS strava.rate_limits;
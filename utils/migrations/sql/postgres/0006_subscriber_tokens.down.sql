@@ -0,0 +1,4 @@
+-- tokens must be decrypted before rolling back, see windspeed rotate-keys
+ALTER TABLE strava.subscribers DROP COLUMN IF EXISTS data_key;
+ALTER TABLE strava.subscribers DROP COLUMN IF EXISTS key_id;
+ALTER TABLE strava.subscribers DROP COLUMN IF EXISTS active;
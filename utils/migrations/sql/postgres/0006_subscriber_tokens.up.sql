@@ -0,0 +1,4 @@
+-- revoked authorizations and encrypted tokens
+ALTER TABLE strava.subscribers ADD COLUMN IF NOT EXISTS active boolean NOT NULL DEFAULT true;
+ALTER TABLE strava.subscribers ADD COLUMN IF NOT EXISTS key_id text NOT NULL DEFAULT '';
+ALTER TABLE strava.subscribers ADD COLUMN IF NOT EXISTS data_key text NOT NULL DEFAULT '';
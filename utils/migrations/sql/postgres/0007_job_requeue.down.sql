@@ -0,0 +1 @@
+ALTER TABLE strava.jobs DROP COLUMN IF EXISTS requeued;
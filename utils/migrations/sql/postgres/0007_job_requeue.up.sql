@@ -0,0 +1,2 @@
+-- jobs enqueued again while running, e.g. for an activity updated meanwhile
+ALTER TABLE strava.jobs ADD COLUMN IF NOT EXISTS requeued boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+ALTER TABLE strava.rate_limits DROP COLUMN IF EXISTS read_short_limit;
+ALTER TABLE strava.rate_limits DROP COLUMN IF EXISTS read_short_usage;
+ALTER TABLE strava.rate_limits DROP COLUMN IF EXISTS read_daily_limit;
+ALTER TABLE strava.rate_limits DROP COLUMN IF EXISTS read_daily_usage;
@@ -0,0 +1,5 @@
+-- the separate budget Strava enforces for reads, e.g. listing activities
+ALTER TABLE strava.rate_limits ADD COLUMN IF NOT EXISTS read_short_limit integer NOT NULL DEFAULT 0;
+ALTER TABLE strava.rate_limits ADD COLUMN IF NOT EXISTS read_short_usage integer NOT NULL DEFAULT 0;
+ALTER TABLE strava.rate_limits ADD COLUMN IF NOT EXISTS read_daily_limit integer NOT NULL DEFAULT 0;
+ALTER TABLE strava.rate_limits ADD COLUMN IF NOT EXISTS read_daily_usage integer NOT NULL DEFAULT 0;
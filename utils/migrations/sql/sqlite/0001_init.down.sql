@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS strava_settings;
+DROP TABLE IF EXISTS strava_subscribers;
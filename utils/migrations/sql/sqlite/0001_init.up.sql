@@ -0,0 +1,20 @@
+-- SQLite has no schemas, so tables are prefixed with the schema name instead.
+
+-- subscribers
+CREATE TABLE IF NOT EXISTS strava_subscribers (
+    id            integer    NOT NULL PRIMARY KEY,
+    access_token  text       NOT NULL,
+    refresh_token text       NOT NULL,
+    expires_at    integer    NOT NULL);
+
+-- settings
+CREATE TABLE IF NOT EXISTS strava_settings (
+    id            integer    NOT NULL PRIMARY KEY,
+    units         text       NOT NULL);
+
+-- events
+CREATE TABLE IF NOT EXISTS events (
+    event_time    int8       NOT NULL,
+    anonymous_id  text       NOT NULL,
+    service       text       NOT NULL,
+    event         text       NOT NULL);
@@ -0,0 +1,6 @@
+ALTER TABLE strava_settings DROP COLUMN destination;
+ALTER TABLE strava_settings DROP COLUMN activity_types;
+ALTER TABLE strava_settings DROP COLUMN pressure_unit;
+ALTER TABLE strava_settings DROP COLUMN wind_unit;
+ALTER TABLE strava_settings DROP COLUMN temperature_unit;
+ALTER TABLE strava_settings DROP COLUMN stamp_template;
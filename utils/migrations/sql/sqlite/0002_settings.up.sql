@@ -0,0 +1,7 @@
+-- stamp format, split units, activity types and destination
+ALTER TABLE strava_settings ADD COLUMN stamp_template text NOT NULL DEFAULT '';
+ALTER TABLE strava_settings ADD COLUMN temperature_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava_settings ADD COLUMN wind_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava_settings ADD COLUMN pressure_unit text NOT NULL DEFAULT '';
+ALTER TABLE strava_settings ADD COLUMN activity_types text NOT NULL DEFAULT '';
+ALTER TABLE strava_settings ADD COLUMN destination text NOT NULL DEFAULT 'description';
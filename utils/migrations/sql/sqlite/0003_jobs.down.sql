@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS strava_backfills;
+DROP TABLE IF EXISTS strava_webhook_events;
+DROP TABLE IF EXISTS strava_jobs;
@@ -0,0 +1,35 @@
+-- jobs
+CREATE TABLE IF NOT EXISTS strava_jobs (
+    id            integer    NOT NULL PRIMARY KEY AUTOINCREMENT,
+    kind          text       NOT NULL,
+    athlete_id    integer    NOT NULL,
+    object_id     int8       NOT NULL,
+    status        text       NOT NULL DEFAULT 'pending',
+    attempts      integer    NOT NULL DEFAULT 0,
+    max_attempts  integer    NOT NULL,
+    run_at        int8       NOT NULL,
+    locked_until  int8       NOT NULL DEFAULT 0,
+    last_error    text       NOT NULL DEFAULT '',
+    created_at    int8       NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_pending_object ON strava_jobs (kind, athlete_id, object_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS jobs_due ON strava_jobs (run_at) WHERE status = 'pending';
+
+-- webhook events, used to skip duplicate deliveries
+CREATE TABLE IF NOT EXISTS strava_webhook_events (
+    owner_id      int8       NOT NULL,
+    object_id     int8       NOT NULL,
+    aspect_type   text       NOT NULL,
+    event_time    int8       NOT NULL,
+    received_at   int8       NOT NULL,
+    PRIMARY KEY (owner_id, object_id, aspect_type, event_time));
+
+-- backfill checkpoints
+CREATE TABLE IF NOT EXISTS strava_backfills (
+    athlete_id    integer    NOT NULL PRIMARY KEY,
+    "after"       int8       NOT NULL,
+    "before"      int8       NOT NULL,
+    checkpoint    int8       NOT NULL,
+    processed     integer    NOT NULL DEFAULT 0,
+    status        text       NOT NULL,
+    updated_at    int8       NOT NULL);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS strava_rate_limits;
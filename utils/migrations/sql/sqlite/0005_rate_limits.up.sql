@@ -0,0 +1,8 @@
+-- strava api usage, shared by all functions
+CREATE TABLE IF NOT EXISTS strava_rate_limits (
+    id            integer    NOT NULL PRIMARY KEY,
+    short_limit   integer    NOT NULL,
+    short_usage   integer    NOT NULL,
+    daily_limit   integer    NOT NULL,
+    daily_usage   integer    NOT NULL,
+    updated_at    int8       NOT NULL);
@@ -0,0 +1,4 @@
+-- tokens must be decrypted before rolling back, see windspeed rotate-keys
+ALTER TABLE strava_subscribers DROP COLUMN data_key;
+ALTER TABLE strava_subscribers DROP COLUMN key_id;
+ALTER TABLE strava_subscribers DROP COLUMN active;
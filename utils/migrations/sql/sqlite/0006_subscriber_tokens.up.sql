@@ -0,0 +1,4 @@
+-- revoked authorizations and encrypted tokens
+ALTER TABLE strava_subscribers ADD COLUMN active boolean NOT NULL DEFAULT true;
+ALTER TABLE strava_subscribers ADD COLUMN key_id text NOT NULL DEFAULT '';
+ALTER TABLE strava_subscribers ADD COLUMN data_key text NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE strava_jobs DROP COLUMN requeued;
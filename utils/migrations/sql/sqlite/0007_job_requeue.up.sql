@@ -0,0 +1,2 @@
+-- jobs enqueued again while running, e.g. for an activity updated meanwhile
+ALTER TABLE strava_jobs ADD COLUMN requeued boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+ALTER TABLE strava_rate_limits DROP COLUMN read_short_limit;
+ALTER TABLE strava_rate_limits DROP COLUMN read_short_usage;
+ALTER TABLE strava_rate_limits DROP COLUMN read_daily_limit;
+ALTER TABLE strava_rate_limits DROP COLUMN read_daily_usage;
@@ -0,0 +1,185 @@
+// Package secrets encrypts values at rest using envelope encryption: each
+// record is sealed with its own random data key, which is in turn sealed with
+// a master key from the environment. Records remember the id of the master
+// key, so keys can be rotated without downtime.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the length of master and data keys, in bytes (AES-256).
+const KeySize int = 32
+
+// Keyring holds the master keys, by id, and the id of the key new records are
+// sealed with. An empty keyring stores values as plain text.
+type Keyring struct {
+	keys    map[string][]byte
+	current string
+}
+
+// Sealed is an encrypted record. KeyId is empty for plain text records.
+type Sealed struct {
+	KeyId   string
+	DataKey string
+	Values  []string
+}
+
+// ErrNoKeys is returned by FromEnv when no key is configured, so records are
+// never stored as plain text by accident.
+var ErrNoKeys = errors.New("no token keys configured, set TOKEN_KEYS, or DEV_MODE=true to store tokens as plain text")
+
+// FromEnv reads the keyring from TOKEN_KEYS, a comma separated list of
+// "id:base64 key" pairs, and TOKEN_KEY_ID, the id of the current key. An
+// empty keyring is only allowed with DEV_MODE=true.
+func FromEnv() (*Keyring, error) {
+	keys := os.Getenv("TOKEN_KEYS")
+	if strings.TrimSpace(keys) == "" && os.Getenv("DEV_MODE") != "true" {
+		return nil, ErrNoKeys
+	}
+	return ParseKeyring(keys, os.Getenv("TOKEN_KEY_ID"))
+}
+
+// ParseKeyring parses a list of "id:base64 key" pairs. If current is empty
+// the last key in the list is used to seal new records.
+func ParseKeyring(keys string, current string) (*Keyring, error) {
+	k := &Keyring{keys: map[string][]byte{}}
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("malformed key %q, expected id:key", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		k.keys[id] = key
+		k.current = id
+	}
+	if current != "" {
+		if _, ok := k.keys[current]; !ok {
+			return nil, fmt.Errorf("current key %q is not in the keyring", current)
+		}
+		k.current = current
+	}
+	return k, nil
+}
+
+// NewKey returns a random master key, base64 encoded.
+func NewKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// CurrentKeyId returns the id of the key new records are sealed with, or an
+// empty string if the keyring is empty.
+func (k *Keyring) CurrentKeyId() string {
+	return k.current
+}
+
+// Seal encrypts the values under a new data key. The associated data, such
+// as the id of the record, must be given again to open it, which stops
+// sealed values from being copied between records.
+func (k *Keyring) Seal(associatedData []byte, values ...string) (Sealed, error) {
+	if k.current == "" {
+		return Sealed{Values: values}, nil
+	}
+
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Sealed{}, err
+	}
+	wrapped, err := seal(k.keys[k.current], dataKey, []byte(k.current))
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	sealed := Sealed{KeyId: k.current, DataKey: wrapped, Values: make([]string, len(values))}
+	for i, value := range values {
+		if sealed.Values[i], err = seal(dataKey, []byte(value), associatedData); err != nil {
+			return Sealed{}, err
+		}
+	}
+	return sealed, nil
+}
+
+// Open decrypts a sealed record.
+func (k *Keyring) Open(associatedData []byte, sealed Sealed) ([]string, error) {
+	if sealed.KeyId == "" {
+		return sealed.Values, nil
+	}
+	key, ok := k.keys[sealed.KeyId]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", sealed.KeyId)
+	}
+	dataKey, err := open(key, sealed.DataKey, []byte(sealed.KeyId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	values := make([]string, len(sealed.Values))
+	for i, value := range sealed.Values {
+		plaintext, err := open(dataKey, value, associatedData)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(plaintext)
+	}
+	return values, nil
+}
+
+// seal encrypts plaintext with AES-GCM, returning the nonce followed by the
+// ciphertext, base64 encoded.
+func seal(key []byte, plaintext []byte, associatedData []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, associatedData)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func open(key []byte, encoded string, associatedData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, associatedData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testKeyring(t *testing.T, keys string, current string) *Keyring {
+	t.Helper()
+	k, err := ParseKeyring(keys, current)
+	if err != nil {
+		t.Fatalf("ParseKeyring(%q, %q) failed: %v", keys, current, err)
+	}
+	return k
+}
+
+func TestParseKeyring(t *testing.T) {
+	first, _ := NewKey()
+	second, _ := NewKey()
+	tests := []struct {
+		name    string
+		keys    string
+		current string
+		want    string
+		valid   bool
+	}{
+		{"empty", "", "", "", true},
+		{"single", "a:" + first, "", "a", true},
+		{"last is current", "a:" + first + ", b:" + second, "", "b", true},
+		{"picked current", "a:" + first + ",b:" + second, "a", "a", true},
+		{"unknown current", "a:" + first, "b", "", false},
+		{"missing id", ":" + first, "", "", false},
+		{"missing separator", first, "", "", false},
+		{"not base64", "a:not-a-key", "", "", false},
+		{"wrong size", "a:c2hvcnQ=", "", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			k, err := ParseKeyring(test.keys, test.current)
+			if (err == nil) != test.valid {
+				t.Fatalf("ParseKeyring error = %v, want valid %v", err, test.valid)
+			}
+			if err == nil && k.CurrentKeyId() != test.want {
+				t.Errorf("CurrentKeyId() = %q, want %q", k.CurrentKeyId(), test.want)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	key, _ := NewKey()
+	tests := []struct {
+		name    string
+		keys    string
+		devMode string
+		want    string
+		err     error
+	}{
+		{"no keys", "", "", "", ErrNoKeys},
+		{"no keys in dev mode", "", "true", "", nil},
+		{"keys", "a:" + key, "", "a", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TOKEN_KEYS", test.keys)
+			t.Setenv("TOKEN_KEY_ID", "")
+			t.Setenv("DEV_MODE", test.devMode)
+			k, err := FromEnv()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("FromEnv error = %v, want %v", err, test.err)
+			}
+			if err == nil && k.CurrentKeyId() != test.want {
+				t.Errorf("CurrentKeyId() = %q, want %q", k.CurrentKeyId(), test.want)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	oldKey, _ := NewKey()
+	newKey, _ := NewKey()
+	plain := testKeyring(t, "", "")
+	old := testKeyring(t, "old:"+oldKey, "")
+	rotated := testKeyring(t, "old:"+oldKey+",new:"+newKey, "new")
+	other := testKeyring(t, "new:"+newKey, "")
+	values := []string{"access", "refresh", ""}
+
+	tests := []struct {
+		name      string
+		seal      *Keyring
+		open      *Keyring
+		sealData  string
+		openData  string
+		wantKeyId string
+		valid     bool
+	}{
+		{"plain text", plain, plain, "42", "42", "", true},
+		{"plain text opened with keys", plain, old, "42", "43", "", true},
+		{"sealed", old, old, "42", "42", "old", true},
+		{"sealed with the old key after rotation", old, rotated, "42", "42", "old", true},
+		{"sealed with the new key", rotated, rotated, "42", "42", "new", true},
+		{"other record", old, old, "42", "43", "old", false},
+		{"unknown key", old, other, "42", "42", "old", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sealed, err := test.seal.Seal([]byte(test.sealData), values...)
+			if err != nil {
+				t.Fatalf("Seal failed: %v", err)
+			}
+			if sealed.KeyId != test.wantKeyId {
+				t.Errorf("sealed with key %q, want %q", sealed.KeyId, test.wantKeyId)
+			}
+			if sealed.KeyId != "" && (sealed.Values[0] == values[0] || sealed.DataKey == "") {
+				t.Errorf("values were not encrypted: %+v", sealed)
+			}
+
+			opened, err := test.open.Open([]byte(test.openData), sealed)
+			if (err == nil) != test.valid {
+				t.Fatalf("Open error = %v, want valid %v", err, test.valid)
+			}
+			if err == nil && !reflect.DeepEqual(opened, values) {
+				t.Errorf("Open = %q, want %q", opened, values)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshDataKeys(t *testing.T) {
+	key, _ := NewKey()
+	k := testKeyring(t, "a:"+key, "")
+	first, _ := k.Seal([]byte("42"), "access")
+	second, _ := k.Seal([]byte("42"), "access")
+	if first.DataKey == second.DataKey || first.Values[0] == second.Values[0] {
+		t.Errorf("sealing twice gave the same data key or ciphertext")
+	}
+}
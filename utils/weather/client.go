@@ -0,0 +1,96 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout bounds a single call to a weather provider.
+const DefaultTimeout time.Duration = 5 * time.Second
+
+// Observation is the weather at a point in time and space, along with the
+// provider that reported it.
+type Observation struct {
+	WeatherData
+	Point
+	Provider string
+}
+
+// Client looks up observations, trying each provider in turn until one of
+// them answers.
+type Client struct {
+	HTTP      *http.Client
+	Providers []Provider
+}
+
+func NewClient() *Client {
+	return &Client{
+		HTTP:      &http.Client{Timeout: DefaultTimeout},
+		Providers: DefaultProviders(),
+	}
+}
+
+// Observe returns the first successful observation. When every provider
+// fails the errors are joined, so errors.Is(err, ErrQuotaExceeded) reports
+// whether any of them ran out of quota and AllFailed whether all of them did.
+func (c *Client) Observe(ctx context.Context, p Point) (Observation, error) {
+	var errs []error
+	for _, provider := range c.Providers {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, requestError(provider.Name(), err))
+			break
+		}
+		data, err := provider.Observe(ctx, c.HTTP, p.Lat, p.Lng, p.Time)
+		if err == nil {
+			return Observation{WeatherData: data, Point: p, Provider: provider.Name()}, nil
+		}
+		log.Printf("> weather provider \"%v\" failed: %v\n", provider.Name(), err)
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return Observation{}, errors.New("no weather providers configured")
+	}
+	return Observation{}, errors.Join(errs...)
+}
+
+// ObserveRoute looks up each sampled point of the route. Samples that fail
+// are dropped, and an error is only returned if none of them succeed.
+func (c *Client) ObserveRoute(ctx context.Context, route Route) ([]Observation, error) {
+	var observations []Observation
+	var firstErr error
+	for _, p := range route.Samples() {
+		o, err := c.Observe(ctx, p)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		observations = append(observations, o)
+	}
+	if len(observations) == 0 {
+		if firstErr == nil {
+			firstErr = &Error{Kind: ErrNoData, Err: errors.New("route has no points")}
+		}
+		return nil, firstErr
+	}
+	return observations, nil
+}
+
+// Report observes the weather along the route and summarizes it.
+func (c *Client) Report(ctx context.Context, route Route) (Report, error) {
+	observations, err := c.ObserveRoute(ctx, route)
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Describe how much of the route was ridden into the wind.
+	report := Report{Summary: Summarize(observations)}
+	if b, ok := Breakdown(route, observations); ok {
+		report.Wind = &b
+	}
+	return report, nil
+}
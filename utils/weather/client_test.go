@@ -0,0 +1,73 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestObserveFallsThrough(t *testing.T) {
+	dt := time.Now().Add(-48 * time.Hour).Truncate(time.Hour).Unix()
+	p := Point{Lat: 52.5, Lng: 13.4, Time: dt}
+	tests := []struct {
+		name     string
+		owm      int
+		meteo    int
+		provider string
+		kinds    []error
+	}{
+		{"first answers", http.StatusOK, http.StatusOK, "openweathermap", nil},
+		{"first over quota", http.StatusTooManyRequests, http.StatusOK, "openmeteo", nil},
+		{"first rejects the key", http.StatusUnauthorized, http.StatusOK, "openmeteo", nil},
+		{"both fail", http.StatusUnauthorized, http.StatusTooManyRequests, "", []error{ErrBadKey, ErrQuotaExceeded}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &fakeProviders{handler: func(w http.ResponseWriter, r *http.Request, host string) {
+				if host == "api.openweathermap.org" {
+					w.WriteHeader(test.owm)
+					fmt.Fprint(w, `{"data":[{"temp":20,"wind_speed":3}]}`)
+					return
+				}
+				w.WriteHeader(test.meteo)
+				fmt.Fprintf(w, `{"hourly":{"time":[%d],"temperature_2m":[18],"wind_speed_10m":[4]}}`, dt)
+			}}
+			client := &Client{HTTP: fake.client(t), Providers: []Provider{OpenWeatherMap{ApiKey: "key"}, OpenMeteo{}}}
+
+			o, err := client.Observe(context.Background(), p)
+			if test.provider == "" {
+				for _, kind := range test.kinds {
+					if !errors.Is(err, kind) {
+						t.Errorf("Observe = %v, want it to include %v", err, kind)
+					}
+				}
+				return
+			}
+			if err != nil || o.Provider != test.provider || o.Point != p {
+				t.Errorf("Observe = %+v, %v, want an observation by %v", o, err, test.provider)
+			}
+			if calls := len(fake.Requests()); test.provider == "openweathermap" && calls != 1 {
+				t.Errorf("%v requests made, want the other providers skipped", calls)
+			}
+		})
+	}
+}
+
+func TestObserveCanceled(t *testing.T) {
+	fake := &fakeProviders{handler: func(w http.ResponseWriter, r *http.Request, host string) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}}
+	client := &Client{HTTP: fake.client(t), Providers: []Provider{OpenWeatherMap{ApiKey: "key"}, OpenMeteo{}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.Observe(ctx, Point{Lat: 52.5, Lng: 13.4, Time: time.Now().Unix()}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Observe = %v, want context.Canceled", err)
+	}
+	if requests := fake.Requests(); len(requests) != 0 {
+		t.Errorf("requests = %v, want none after cancelation", requests)
+	}
+}
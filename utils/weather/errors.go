@@ -0,0 +1,94 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("weather provider quota exceeded")
+	ErrBadKey        = errors.New("weather provider rejected the api key")
+	ErrNoData        = errors.New("weather provider returned no data")
+	ErrTimeout       = errors.New("weather provider timed out")
+)
+
+// Error records which provider failed. errors.Is matches both the kind of
+// failure (one of the Err* values above, if known) and the underlying error.
+type Error struct {
+	Provider string
+	Kind     error
+	Err      error
+}
+
+func (e *Error) Error() string {
+	if e.Kind == nil {
+		return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+	}
+	return fmt.Sprintf("%s: %v: %v", e.Provider, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// statusError maps a non 200 response to the kind of failure it represents.
+func statusError(provider string, resp *http.Response) error {
+	err := fmt.Errorf("unexpected response status code: %d", resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &Error{Provider: provider, Kind: ErrBadKey, Err: err}
+	case http.StatusTooManyRequests:
+		return &Error{Provider: provider, Kind: ErrQuotaExceeded, Err: err}
+	case http.StatusGatewayTimeout:
+		return &Error{Provider: provider, Kind: ErrTimeout, Err: err}
+	}
+	return &Error{Provider: provider, Err: err}
+}
+
+// requestError wraps a failed request, recognizing timeouts.
+func requestError(provider string, err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{Provider: provider, Kind: ErrTimeout, Err: err}
+	}
+	return &Error{Provider: provider, Err: err}
+}
+
+// AllFailed reports whether every provider failure joined into err, see
+// Client.Observe, is of one of the given kinds. A single provider running out
+// of quota says nothing about the others.
+func AllFailed(err error, kinds ...error) bool {
+	if providerErr, ok := err.(*Error); ok {
+		return isKind(providerErr, kinds)
+	}
+	switch wrapped := err.(type) {
+	case nil:
+		return false
+	case interface{ Unwrap() []error }:
+		errs := wrapped.Unwrap()
+		for _, e := range errs {
+			if !AllFailed(e, kinds...) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return AllFailed(wrapped.Unwrap(), kinds...)
+	}
+	return isKind(err, kinds)
+}
+
+func isKind(err error, kinds []error) bool {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestAllFailed(t *testing.T) {
+	quota := &Error{Provider: "a", Kind: ErrQuotaExceeded, Err: errors.New("429")}
+	badKey := &Error{Provider: "b", Kind: ErrBadKey, Err: errors.New("401")}
+	noData := &Error{Provider: "c", Kind: ErrNoData, Err: errors.New("empty")}
+	timeout := &Error{Provider: "d", Kind: ErrTimeout, Err: errors.New("deadline")}
+
+	tests := []struct {
+		name      string
+		err       error
+		quota     bool
+		permanent bool
+	}{
+		{"no error", nil, false, false},
+		{"single provider over quota", quota, true, false},
+		{"every provider over quota", errors.Join(quota, quota), true, false},
+		{"one provider over quota", errors.Join(quota, badKey), false, false},
+		{"every provider failed permanently", errors.Join(badKey, noData), false, true},
+		{"one provider timed out", errors.Join(badKey, timeout), false, false},
+		{"wrapped", fmt.Errorf("lookup failed: %w", errors.Join(noData, noData)), false, true},
+		{"unknown failure", errors.Join(badKey, errors.New("boom")), false, false},
+	}
+	for _, test := range tests {
+		if got := AllFailed(test.err, ErrQuotaExceeded); got != test.quota {
+			t.Errorf("%v: AllFailed(quota) = %v, want %v", test.name, got, test.quota)
+		}
+		if got := AllFailed(test.err, ErrBadKey, ErrNoData); got != test.permanent {
+			t.Errorf("%v: AllFailed(bad key, no data) = %v, want %v", test.name, got, test.permanent)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// OpenMeteo uses the free Open-Meteo APIs, which serve hourly history for
+// past dates without an API key.
+type OpenMeteo struct{}
+
+// OpenMeteoArchiveDelay is how far behind the historical weather archive
+// lags. More recent days are read from the forecast API instead, which only
+// covers the last few months.
+const OpenMeteoArchiveDelay time.Duration = 7 * 24 * time.Hour
+
+// Endpoints of the Open-Meteo APIs.
+const (
+	OpenMeteoArchiveURL  string = "https://archive-api.open-meteo.com/v1/archive"
+	OpenMeteoForecastURL string = "https://api.open-meteo.com/v1/forecast"
+)
+
+// openMeteoHourly are the hourly variables requested. The archive has no UV
+// index.
+const openMeteoHourly string = "temperature_2m,relative_humidity_2m,dew_point_2m,apparent_temperature,pressure_msl,cloud_cover,wind_speed_10m,wind_direction_10m,wind_gusts_10m,precipitation"
+
+type openMeteoResponse struct {
+	Hourly struct {
+		Time                []int64   `json:"time"`
+		Temperature         []float32 `json:"temperature_2m"`
+		RelativeHumidity    []float32 `json:"relative_humidity_2m"`
+		DewPoint            []float32 `json:"dew_point_2m"`
+		ApparentTemperature []float32 `json:"apparent_temperature"`
+		PressureMsl         []float32 `json:"pressure_msl"`
+		CloudCover          []float32 `json:"cloud_cover"`
+		UvIndex             []float32 `json:"uv_index"`
+		WindSpeed           []float32 `json:"wind_speed_10m"`
+		WindDirection       []float64 `json:"wind_direction_10m"`
+		WindGusts           []float32 `json:"wind_gusts_10m"`
+		Precipitation       []float32 `json:"precipitation"`
+	} `json:"hourly"`
+}
+
+func (p OpenMeteo) Name() string {
+	return "openmeteo"
+}
+
+func (p OpenMeteo) Observe(ctx context.Context, c *http.Client, lat float64, lng float64, dt int64) (WeatherData, error) {
+	// Construct weather API request, in the same units OpenWeatherMap returns.
+	day := time.Unix(dt, 0).UTC().Format("2006-01-02")
+	params := url.Values{}
+	params.Add("latitude", fmt.Sprintf("%f", lat))
+	params.Add("longitude", fmt.Sprintf("%f", lng))
+	params.Add("start_date", day)
+	params.Add("end_date", day)
+	endpoint, hourly := OpenMeteoArchiveURL, openMeteoHourly
+	if time.Since(time.Unix(dt, 0)) < OpenMeteoArchiveDelay {
+		endpoint, hourly = OpenMeteoForecastURL, openMeteoHourly+",uv_index"
+	}
+	params.Add("hourly", hourly)
+	params.Add("timeformat", "unixtime")
+	params.Add("timezone", "GMT")
+	params.Add("wind_speed_unit", "ms")
+
+	// Call the weather API.
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return WeatherData{}, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return WeatherData{}, requestError(p.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return WeatherData{}, statusError(p.Name(), resp)
+	}
+
+	// Parse API response.
+	var mResp openMeteoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&mResp); err != nil {
+		return WeatherData{}, requestError(p.Name(), err)
+	}
+	h := mResp.Hourly
+	if len(h.Time) == 0 {
+		return WeatherData{}, &Error{Provider: p.Name(), Kind: ErrNoData, Err: errors.New("empty response")}
+	}
+
+	// Pick the hour closest to the requested time.
+	i := 0
+	for j, t := range h.Time {
+		if math.Abs(float64(t-dt)) < math.Abs(float64(h.Time[i]-dt)) {
+			i = j
+		}
+	}
+	at := func(values []float32) float32 {
+		if i < len(values) {
+			return values[i]
+		}
+		return 0
+	}
+
+	data := WeatherData{
+		Clouds:     uint16(at(h.CloudCover)),
+		Dew_point:  at(h.DewPoint),
+		Feels_like: at(h.ApparentTemperature),
+		Humidity:   uint8(at(h.RelativeHumidity)),
+		Pressure:   at(h.PressureMsl),
+		Temp:       at(h.Temperature),
+		Uvi:        at(h.UvIndex),
+		Wind_speed: at(h.WindSpeed),
+		Wind_gust:  at(h.WindGusts),
+		Rain:       at(h.Precipitation),
+	}
+	if i < len(h.WindDirection) {
+		data.Wind_deg = h.WindDirection[i]
+	}
+	return data, nil
+}
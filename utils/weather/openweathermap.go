@@ -0,0 +1,64 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// OpenWeatherMap uses the One Call 3.0 timemachine endpoint.
+type OpenWeatherMap struct {
+	ApiKey string
+}
+
+type owmResponse struct {
+	Data []owmData
+}
+
+type owmData struct {
+	WeatherData
+	Rain struct {
+		OneHour float32 `json:"1h"`
+	}
+}
+
+func (p OpenWeatherMap) Name() string {
+	return "openweathermap"
+}
+
+func (p OpenWeatherMap) Observe(ctx context.Context, c *http.Client, lat float64, lng float64, dt int64) (WeatherData, error) {
+	// Construct weather API request.
+	var url string = "https://api.openweathermap.org/data/3.0/onecall/timemachine?"
+	url += fmt.Sprintf("lat=%f&lon=%f", lat, lng)
+	url += fmt.Sprintf("&dt=%d&units=metric", dt)
+	url += fmt.Sprintf("&appid=%s", p.ApiKey)
+
+	// Call the weather API.
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return WeatherData{}, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return WeatherData{}, requestError(p.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return WeatherData{}, statusError(p.Name(), resp)
+	}
+
+	// Parse API response.
+	var wResp owmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&wResp); err != nil {
+		return WeatherData{}, requestError(p.Name(), err)
+	}
+	if len(wResp.Data) == 0 {
+		return WeatherData{}, &Error{Provider: p.Name(), Kind: ErrNoData, Err: errors.New("empty response")}
+	}
+	data := wResp.Data[0].WeatherData
+	data.Rain = wResp.Data[0].Rain.OneHour
+	return data, nil
+}
@@ -0,0 +1,47 @@
+package weather
+
+import (
+	"regexp"
+	"strings"
+)
+
+// stampPattern matches stamps rendered with DefaultTemplate, as well as
+// those from before stamps could span a temperature range or report rain.
+var stampPattern = regexp.MustCompile(`-?\d+\.\d(?:–-?\d+\.\d)?°[CF]?, clouds: \d+%, humidity: \d+%, wind: \d+(?:\.\d)?(?: \(\d+(?:\.\d)? gust\))?\s*(?:(?:mph|km/h|m/s|kn|Bft)\s*)?[↓↙←↖↑↗→↘](?:, rain: \d+\.\d mm)?(?:\nheadwind: \d+%, tailwind: \d+%, crosswind: \d+% \(avg headwind: -?\d+(?:\.\d)? \S+\))?`)
+
+// shortStampPattern matches stamps added to activity names by ShortStamp.
+var shortStampPattern = regexp.MustCompile(`🌬 \d+ (?:mph|km/h|m/s|kn|Bft) (?:N|NE|E|SE|S|SW|W|NW)$`)
+
+// KnownSignatures are fragments other weather services add to the activities
+// they describe. Activities that already carry one are not stamped again.
+var KnownSignatures = []string{
+	"klimat.app",
+	"mywindsock.com",
+	"weatherfor.run",
+}
+
+// FindStamp returns the windspeed.app stamp (in the default format) found
+// in the description, if any.
+func FindStamp(description string) (string, bool) {
+	stamp := stampPattern.FindString(strings.ReplaceAll(description, "\r\n", "\n"))
+	return stamp, stamp != ""
+}
+
+// HasStamp reports whether the text (a description, name or private note)
+// already carries weather details, either a windspeed.app stamp or one of a
+// known similar service.
+func HasStamp(description string) bool {
+	if _, ok := FindStamp(description); ok {
+		return true
+	}
+	if shortStampPattern.MatchString(strings.TrimSpace(description)) {
+		return true
+	}
+	lower := strings.ToLower(description)
+	for _, signature := range KnownSignatures {
+		if strings.Contains(lower, signature) {
+			return true
+		}
+	}
+	return false
+}
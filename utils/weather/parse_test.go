@@ -0,0 +1,88 @@
+package weather
+
+import (
+	"testing"
+)
+
+func TestFindStamp(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		stamp       string
+	}{
+		{"empty", "", ""},
+		{"no stamp", "Morning ride with the club", ""},
+		{
+			"stamp only",
+			"68.5°F, clouds: 20%, humidity: 55%, wind: 8.1 mph ↗",
+			"68.5°F, clouds: 20%, humidity: 55%, wind: 8.1 mph ↗",
+		},
+		{
+			"after the users text",
+			"Morning ride\n20.1°C, clouds: 0%, humidity: 40%, wind: 12.6 (20.2 gust) km/h ←, rain: 0.4 mm",
+			"20.1°C, clouds: 0%, humidity: 40%, wind: 12.6 (20.2 gust) km/h ←, rain: 0.4 mm",
+		},
+		{
+			"temperature range",
+			"-2.5–1.0°C, clouds: 100%, humidity: 90%, wind: 3 Bft ↓",
+			"-2.5–1.0°C, clouds: 100%, humidity: 90%, wind: 3 Bft ↓",
+		},
+		{
+			"with wind components",
+			"10.0°C, clouds: 10%, humidity: 60%, wind: 4.2 m/s →\r\nheadwind: 30%, tailwind: 50%, crosswind: 20% (avg headwind: -1.3 m/s)",
+			"10.0°C, clouds: 10%, humidity: 60%, wind: 4.2 m/s →\nheadwind: 30%, tailwind: 50%, crosswind: 20% (avg headwind: -1.3 m/s)",
+		},
+		{
+			"with wind components in Beaufort",
+			"2.0°C, clouds: 10%, humidity: 60%, wind: 4 Bft →\nheadwind: 30%, tailwind: 50%, crosswind: 20% (avg headwind: -2 Bft)",
+			"2.0°C, clouds: 10%, humidity: 60%, wind: 4 Bft →\nheadwind: 30%, tailwind: 50%, crosswind: 20% (avg headwind: -2 Bft)",
+		},
+		{"legacy without units", "Ride\n15.0°, clouds: 5%, humidity: 30%, wind: 2.0 ↑", "15.0°, clouds: 5%, humidity: 30%, wind: 2.0 ↑"},
+		{"custom template", "Temperature 15.0°C, wind 2.0 km/h", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stamp, ok := FindStamp(test.description)
+			if stamp != test.stamp || ok != (test.stamp != "") {
+				t.Errorf("FindStamp(%q) = %q, %v, want %q", test.description, stamp, ok, test.stamp)
+			}
+		})
+	}
+}
+
+func TestFindStampDefaultTemplate(t *testing.T) {
+	for _, wind := range WindUnits {
+		units := Units{Temperature: Celsius, Wind: wind, Pressure: Hectopascal}
+		preview, err := PreviewStamp(DefaultTemplate, units)
+		if err != nil {
+			t.Fatalf("PreviewStamp(%v) failed: %v", units, err)
+		}
+		if stamp, ok := FindStamp("Evening run\n" + preview); !ok || stamp != preview {
+			t.Errorf("FindStamp did not find the %v stamp %q, got %q", wind, preview, stamp)
+		}
+	}
+}
+
+func TestHasStamp(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        bool
+	}{
+		{"empty", "", false},
+		{"no stamp", "Lunch run", false},
+		{"stamp", "Lunch run\n68.5°F, clouds: 20%, humidity: 55%, wind: 8.1 mph ↗", true},
+		{"short stamp", "Lunch run 🌬 12 km/h NW", true},
+		{"short stamp with trailing space", "Lunch run 🌬 3 Bft S ", true},
+		{"short stamp mid name", "🌬 12 km/h NW lunch run", false},
+		{"known signature", "Sunny, 21°C - Weather by Klimat.app", true},
+		{"other signature", "Headwind 12 km/h via mywindsock.com", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HasStamp(test.description); got != test.want {
+				t.Errorf("HasStamp(%q) = %v, want %v", test.description, got, test.want)
+			}
+		})
+	}
+}
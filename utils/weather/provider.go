@@ -0,0 +1,39 @@
+package weather
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Provider is a source of historical weather observations, reported in °C,
+// m/s and hPa whatever the users preferred units are.
+type Provider interface {
+	Name() string
+	Observe(ctx context.Context, c *http.Client, lat float64, lng float64, dt int64) (WeatherData, error)
+}
+
+// DefaultProviders builds the fallback chain from the comma separated
+// WEATHER_PROVIDERS variable, e.g. "openweathermap,openmeteo".
+func DefaultProviders() []Provider {
+	names := os.Getenv("WEATHER_PROVIDERS")
+	if names == "" {
+		names = "openweathermap,openmeteo"
+	}
+
+	var providers []Provider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "openweathermap", "owm":
+			providers = append(providers, OpenWeatherMap{ApiKey: os.Getenv("WEATHER_API_KEY")})
+		case "openmeteo", "open-meteo":
+			providers = append(providers, OpenMeteo{})
+		case "":
+		default:
+			log.Printf("> unknown weather provider \"%v\". Skipping...\n", name)
+		}
+	}
+	return providers
+}
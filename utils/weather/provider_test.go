@@ -0,0 +1,123 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProviders serves both providers' APIs from one httptest server. The
+// client it returns sends every request there, whatever its host.
+type fakeProviders struct {
+	mu       sync.Mutex
+	requests []*url.URL
+	handler  func(w http.ResponseWriter, r *http.Request, host string)
+}
+
+func (f *fakeProviders) client(t *testing.T) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.handler(w, r, r.Header.Get("X-Original-Host"))
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return &http.Client{Transport: roundTripper(func(r *http.Request) (*http.Response, error) {
+		f.mu.Lock()
+		f.requests = append(f.requests, r.URL)
+		f.mu.Unlock()
+		r = r.Clone(r.Context())
+		r.Header.Set("X-Original-Host", r.URL.Host)
+		r.URL.Scheme, r.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+}
+
+func (f *fakeProviders) Requests() []*url.URL {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*url.URL(nil), f.requests...)
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestProviderStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		kind   error
+	}{
+		{http.StatusUnauthorized, `{}`, ErrBadKey},
+		{http.StatusForbidden, `{}`, ErrBadKey},
+		{http.StatusTooManyRequests, `{}`, ErrQuotaExceeded},
+		{http.StatusGatewayTimeout, `{}`, ErrTimeout},
+		{http.StatusInternalServerError, `{}`, nil},
+		{http.StatusOK, `{"data":[],"hourly":{"time":[]}}`, ErrNoData},
+	}
+	for _, provider := range []Provider{OpenWeatherMap{ApiKey: "key"}, OpenMeteo{}} {
+		for _, test := range tests {
+			t.Run(fmt.Sprintf("%v %v", provider.Name(), test.status), func(t *testing.T) {
+				fake := &fakeProviders{handler: func(w http.ResponseWriter, r *http.Request, host string) {
+					w.WriteHeader(test.status)
+					fmt.Fprint(w, test.body)
+				}}
+				_, err := provider.Observe(context.Background(), fake.client(t), 52.5, 13.4, time.Now().Add(-time.Hour).Unix())
+
+				var providerErr *Error
+				if !errors.As(err, &providerErr) || providerErr.Provider != provider.Name() || providerErr.Kind != test.kind {
+					t.Errorf("Observe = %v, want a %v error of kind %v", err, provider.Name(), test.kind)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenMeteoEndpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		age  time.Duration
+		host string
+		uvi  bool
+	}{
+		{"recent", 24 * time.Hour, "api.open-meteo.com", true},
+		{"archived", 30 * 24 * time.Hour, "archive-api.open-meteo.com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dt := time.Now().Add(-test.age).Truncate(time.Hour).Unix()
+			fake := &fakeProviders{handler: func(w http.ResponseWriter, r *http.Request, host string) {
+				fmt.Fprintf(w, `{"hourly":{"time":[%d,%d],"temperature_2m":[10,12],"wind_direction_10m":[90,180],"uv_index":[1,2]}}`, dt-3600, dt)
+			}}
+			data, err := OpenMeteo{}.Observe(context.Background(), fake.client(t), 52.5, 13.4, dt+600)
+			if err != nil {
+				t.Fatalf("Observe failed: %v", err)
+			}
+
+			requests := fake.Requests()
+			if len(requests) != 1 || requests[0].Host != test.host {
+				t.Fatalf("requests = %v, want one to %v", requests, test.host)
+			}
+			query := requests[0].Query()
+			if hasUvi := strings.Contains(query.Get("hourly"), "uv_index"); hasUvi != test.uvi {
+				t.Errorf("hourly = %q, want uv_index %v", query.Get("hourly"), test.uvi)
+			}
+			if day := time.Unix(dt, 0).UTC().Format("2006-01-02"); query.Get("start_date") != day || query.Get("end_date") != day {
+				t.Errorf("requested %v to %v, want %v", query.Get("start_date"), query.Get("end_date"), day)
+			}
+			// The hour closest to the activity is picked.
+			if data.Temp != 12 || data.Wind_deg != 180 {
+				t.Errorf("Observe = %+v, want the second hour", data)
+			}
+		})
+	}
+}
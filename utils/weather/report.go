@@ -0,0 +1,49 @@
+package weather
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// Report is everything a stamp is rendered from. It is kept alongside each
+// stamp so that the stamp can be rendered again, e.g. in other units.
+type Report struct {
+	Summary Summary
+	Wind    *WindBreakdown
+}
+
+// RenderStamp renders the report with the users template, falling back to
+// the default one if it is invalid.
+func RenderStamp(report Report, units Units, stampTemplate string) (string, error) {
+	fields := stampFields(report.Summary, report.Wind, units)
+
+	tmpl, err := ParseTemplate(stampTemplate)
+	if err != nil {
+		log.Printf("> invalid stamp template: %v\n", err)
+		tmpl, _ = ParseTemplate(DefaultTemplate)
+	}
+	wStamp, err := render(tmpl, fields)
+	if err != nil {
+		log.Printf("> failed to render stamp template: %v\n", err)
+		tmpl, _ = ParseTemplate(DefaultTemplate)
+		return render(tmpl, fields)
+	}
+	return wStamp, nil
+}
+
+var compassPoints = [...]string{"N", "NE", "E", "SE", "S", "SW", "W", "NW", "N"}
+
+// ShortStamp is a one line summary of the wind that fits behind an activity
+// name, e.g. "🌬 18 km/h NE". The direction is where the wind comes from.
+func ShortStamp(report Report, units Units) string {
+	s := report.Summary
+	direction := compassPoints[int(math.Round(s.Wind_deg/45))]
+	speed := fmt.Sprintf("%.0f", units.windSpeed(s.Wind_speed))
+	return fmt.Sprintf("🌬 %s %s %s", speed, units.windLabel(), direction)
+}
+
+// PreviewShortStamp renders a short stamp using made up weather details.
+func PreviewShortStamp(units Units) string {
+	return ShortStamp(Report{Summary: sampleSummary, Wind: &sampleBreakdown}, units)
+}
@@ -0,0 +1,106 @@
+package weather
+
+import (
+	"math"
+)
+
+// MaxSamples caps the number of weather lookups made for a single activity.
+const MaxSamples int = 6
+
+// SampleInterval is the preferred time between two weather samples, in seconds.
+const SampleInterval int64 = 3600
+
+// Point is a position along an activity at a given unix time.
+type Point struct {
+	Lat  float64
+	Lng  float64
+	Time int64
+}
+
+// Route is the ordered list of points recorded during an activity.
+type Route []Point
+
+// Samples picks points spread evenly in time along the route: the start, the
+// end, and roughly one per SampleInterval in between (at most MaxSamples).
+func (r Route) Samples() Route {
+	if len(r) <= 1 {
+		return r
+	}
+
+	duration := r[len(r)-1].Time - r[0].Time
+	n := int(duration/SampleInterval) + 2
+	if n > MaxSamples {
+		n = MaxSamples
+	}
+
+	var samples Route
+	j := 0
+	for i := 0; i < n; i++ {
+		t := r[0].Time + duration*int64(i)/int64(n-1)
+		for j < len(r)-1 && r[j].Time < t {
+			j++
+		}
+		if len(samples) == 0 || samples[len(samples)-1] != r[j] {
+			samples = append(samples, r[j])
+		}
+	}
+	return samples
+}
+
+// Summary combines the observations made along a route.
+type Summary struct {
+	Clouds     uint16
+	Dew_point  float32
+	Feels_like float32
+	Humidity   uint8
+	Pressure   float32
+	TempMin    float32
+	TempMax    float32
+	Uvi        float32
+	Wind_speed float32
+	Wind_deg   float64
+	GustMax    float32
+	Rain       float32
+}
+
+// Summarize averages most values over all observations, and
+// keeps the temperature range, strongest gust and total rain.
+func Summarize(observations []Observation) Summary {
+	var s Summary
+	if len(observations) == 0 {
+		return s
+	}
+
+	s.TempMin = observations[0].Temp
+	s.TempMax = observations[0].Temp
+	var clouds, dewPoint, feelsLike, humidity, pressure, uvi, windSpeed, u, v float64
+	for _, o := range observations {
+		s.TempMin = float32(math.Min(float64(s.TempMin), float64(o.Temp)))
+		s.TempMax = float32(math.Max(float64(s.TempMax), float64(o.Temp)))
+		s.GustMax = float32(math.Max(float64(s.GustMax), float64(o.Wind_gust)))
+		s.Rain += o.Rain
+		clouds += float64(o.Clouds)
+		dewPoint += float64(o.Dew_point)
+		feelsLike += float64(o.Feels_like)
+		humidity += float64(o.Humidity)
+		pressure += float64(o.Pressure)
+		uvi += float64(o.Uvi)
+		windSpeed += float64(o.Wind_speed)
+
+		// Wind directions are averaged as vectors so that 350° and 10° give 0°.
+		rad := o.Wind_deg * math.Pi / 180
+		u += math.Sin(rad)
+		v += math.Cos(rad)
+	}
+
+	n := float64(len(observations))
+	s.Clouds = uint16(math.Round(clouds / n))
+	s.Dew_point = float32(dewPoint / n)
+	s.Feels_like = float32(feelsLike / n)
+	s.Humidity = uint8(math.Round(humidity / n))
+	s.Pressure = float32(pressure / n)
+	s.Uvi = float32(uvi / n)
+	s.Wind_speed = float32(windSpeed / n)
+	s.Wind_deg = math.Mod(math.Atan2(u, v)*180/math.Pi+360, 360)
+	return s
+}
@@ -0,0 +1,81 @@
+package weather
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// straightRoute records a point every step seconds for duration seconds.
+func straightRoute(duration int64, step int64) Route {
+	var r Route
+	for t := int64(0); t <= duration; t += step {
+		r = append(r, Point{Lat: 52 + float64(t)/1e5, Lng: 13, Time: 1000 + t})
+	}
+	return r
+}
+
+func TestSamples(t *testing.T) {
+	sparse := Route{{Lat: 52, Lng: 13, Time: 1000}, {Lat: 52.1, Lng: 13, Time: 11000}}
+	tests := []struct {
+		name  string
+		route Route
+		times []int64
+	}{
+		{"empty", nil, nil},
+		{"single point", Route{{Lat: 52, Lng: 13, Time: 1000}}, []int64{1000}},
+		{"short", straightRoute(1800, 60), []int64{1000, 2800}},
+		{"one per interval", straightRoute(9000, 60), []int64{1000, 4000, 7000, 10000}},
+		{"capped", straightRoute(36000, 60), []int64{1000, 8200, 15400, 22600, 29800, 37000}},
+		{"no duplicates", sparse, []int64{1000, 11000}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var times []int64
+			for _, p := range test.route.Samples() {
+				times = append(times, p.Time)
+			}
+			if !reflect.DeepEqual(times, test.times) {
+				t.Errorf("Samples() at %v, want %v", times, test.times)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	observation := func(temp float32, gust float32, rain float32, deg float64, humidity uint8) Observation {
+		return Observation{WeatherData: WeatherData{Temp: temp, Wind_gust: gust, Rain: rain, Wind_deg: deg, Wind_speed: 4, Humidity: humidity, Clouds: 50}}
+	}
+	tests := []struct {
+		name         string
+		observations []Observation
+		want         Summary
+	}{
+		{"none", nil, Summary{}},
+		{
+			"single",
+			[]Observation{observation(12, 8, 0.5, 90, 60)},
+			Summary{TempMin: 12, TempMax: 12, GustMax: 8, Rain: 0.5, Wind_deg: 90, Wind_speed: 4, Humidity: 60, Clouds: 50},
+		},
+		{
+			"range, strongest gust and total rain",
+			[]Observation{observation(10, 6, 0.2, 350, 60), observation(14, 11, 0, 10, 70), observation(12, 9, 1.3, 0, 71)},
+			Summary{TempMin: 10, TempMax: 14, GustMax: 11, Rain: 1.5, Wind_deg: 0, Wind_speed: 4, Humidity: 67, Clouds: 50},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := Summarize(test.observations)
+			// Wind directions around north may come out as 359.99…
+			if math.Abs(math.Mod(s.Wind_deg-test.want.Wind_deg+540, 360)-180) < 1e-6 {
+				s.Wind_deg = test.want.Wind_deg
+			}
+			if math.Abs(float64(s.Rain-test.want.Rain)) < 1e-6 {
+				s.Rain = test.want.Rain
+			}
+			if s != test.want {
+				t.Errorf("Summarize = %+v, want %+v", s, test.want)
+			}
+		})
+	}
+}
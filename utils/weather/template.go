@@ -0,0 +1,206 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// MaxTemplateLength limits the size of a user supplied stamp template.
+const MaxTemplateLength int = 500
+
+// MaxStampLength limits the size of a rendered stamp.
+const MaxStampLength int = 500
+
+// DefaultTemplate renders the original windspeed.app stamp.
+const DefaultTemplate string = `{{.temp}}{{.temp_unit}}, clouds: {{.clouds}}%, humidity: {{.humidity}}%, wind: {{.wind}}{{if .gust}} ({{.gust}} gust){{end}} {{.wind_unit}} {{.wind_dir}}{{if .rain}}, rain: {{.rain}} mm{{end}}{{if .headwind}}
+headwind: {{.headwind}}%, tailwind: {{.tailwind}}%, crosswind: {{.crosswind}}% (avg headwind: {{.avg_headwind}} {{.wind_unit}}){{end}}`
+
+// TemplateFields lists the placeholders available to stamp templates.
+var TemplateFields = []string{
+	"temp", "temp_min", "temp_max", "temp_unit", "feels_like", "dew_point",
+	"pressure", "pressure_unit", "uvi", "clouds", "humidity", "wind", "gust", "wind_unit",
+	"wind_dir", "rain", "headwind", "tailwind", "crosswind", "avg_headwind",
+}
+
+// sampleSummary is used to validate and preview templates without calling a
+// weather provider.
+var sampleSummary = Summary{
+	Clouds:     40,
+	Dew_point:  8.2,
+	Feels_like: 13.9,
+	Humidity:   72,
+	Pressure:   1016,
+	TempMin:    14.1,
+	TempMax:    17.6,
+	Uvi:        3.4,
+	Wind_speed: 4.6,
+	Wind_deg:   225,
+	GustMax:    8.3,
+	Rain:       0.4,
+}
+
+var sampleBreakdown = WindBreakdown{Headwind: 38, Tailwind: 27, Crosswind: 35, AvgHeadwind: 1.2}
+
+// ParseTemplate checks that a stamp template is short enough, only uses
+// known placeholders and conditions on them, and renders a stamp within
+// MaxStampLength.
+func ParseTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultTemplate
+	}
+	if len(text) > MaxTemplateLength {
+		return nil, fmt.Errorf("template is longer than %d characters", MaxTemplateLength)
+	}
+
+	tmpl, err := template.New("stamp").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// {{define}} adds templates next to the stamp, outside of its tree.
+	if len(tmpl.Templates()) > 1 {
+		return nil, errTemplateNode
+	}
+	if err := checkNodes(tmpl.Tree.Root); err != nil {
+		return nil, err
+	}
+	if _, err := render(tmpl, stampFields(sampleSummary, &sampleBreakdown, Metric)); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// PreviewStamp renders a template using made up weather details.
+func PreviewStamp(text string, units Units) (string, error) {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	return render(tmpl, stampFields(sampleSummary, &sampleBreakdown, units))
+}
+
+// errTemplateNode is returned for anything but text, {{.field}} and
+// {{if .field}}...{{else}}...{{end}}. Loops, functions and nested templates
+// would let a template run for as long as it likes.
+var errTemplateNode = errors.New("templates can only use {{.field}} and {{if .field}}...{{end}}")
+
+func checkNodes(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode, *parse.CommentNode:
+		case *parse.ActionNode:
+			if err := checkField(n.Pipe); err != nil {
+				return err
+			}
+		case *parse.IfNode:
+			if err := checkField(n.Pipe); err != nil {
+				return err
+			}
+			if err := checkNodes(n.List); err != nil {
+				return err
+			}
+			if err := checkNodes(n.ElseList); err != nil {
+				return err
+			}
+		default:
+			return errTemplateNode
+		}
+	}
+	return nil
+}
+
+// checkField allows a pipeline made of a single known field, e.g. .temp.
+func checkField(pipe *parse.PipeNode) error {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return errTemplateNode
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return errTemplateNode
+	}
+	for _, name := range TemplateFields {
+		if field.Ident[0] == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field: %q", field.Ident[0])
+}
+
+// errStampTooLong stops rendering once the stamp can't fit anymore.
+var errStampTooLong = fmt.Errorf("stamp is longer than %d characters", MaxStampLength)
+
+// limitedWriter refuses to grow past MaxStampLength.
+type limitedWriter struct {
+	strings.Builder
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > MaxStampLength {
+		return 0, errStampTooLong
+	}
+	return w.Builder.Write(p)
+}
+
+func render(tmpl *template.Template, fields map[string]string) (string, error) {
+	var sb limitedWriter
+	if err := tmpl.Execute(&sb, fields); err != nil {
+		if errors.Is(err, errStampTooLong) {
+			return "", errStampTooLong
+		}
+		return "", err
+	}
+	stamp := strings.TrimSpace(sb.String())
+	if stamp == "" {
+		return "", errors.New("template renders an empty stamp")
+	}
+	return stamp, nil
+}
+
+// stampFields formats the weather summary for use in a template. Optional
+// values (gust, rain and the wind breakdown) are left empty when missing.
+func stampFields(s Summary, b *WindBreakdown, units Units) map[string]string {
+	fields := map[string]string{
+		"temp":          fmt.Sprintf("%0.1f", units.temperature(s.TempMin)),
+		"temp_min":      fmt.Sprintf("%0.1f", units.temperature(s.TempMin)),
+		"temp_max":      fmt.Sprintf("%0.1f", units.temperature(s.TempMax)),
+		"temp_unit":     units.tempLabel(),
+		"feels_like":    fmt.Sprintf("%0.1f", units.temperature(s.Feels_like)),
+		"dew_point":     fmt.Sprintf("%0.1f", units.temperature(s.Dew_point)),
+		"pressure":      units.formatPressure(s.Pressure),
+		"pressure_unit": units.Pressure,
+		"uvi":           fmt.Sprintf("%0.1f", s.Uvi),
+		"clouds":        fmt.Sprintf("%d", s.Clouds),
+		"humidity":      fmt.Sprintf("%d", s.Humidity),
+		"wind":          units.formatWind(s.Wind_speed),
+		"gust":          "",
+		"wind_unit":     units.windLabel(),
+		"wind_dir":      windArrows[int(math.Round(s.Wind_deg/45))],
+		"rain":          "",
+		"headwind":      "",
+		"tailwind":      "",
+		"crosswind":     "",
+		"avg_headwind":  "",
+	}
+	if fields["temp_min"] != fields["temp_max"] {
+		fields["temp"] = fields["temp_min"] + "–" + fields["temp_max"]
+	}
+	if s.GustMax > 0 {
+		fields["gust"] = units.formatWind(s.GustMax)
+	}
+	if s.Rain > 0 {
+		fields["rain"] = fmt.Sprintf("%0.1f", s.Rain)
+	}
+	if b != nil {
+		fields["headwind"] = fmt.Sprintf("%.0f", b.Headwind)
+		fields["tailwind"] = fmt.Sprintf("%.0f", b.Tailwind)
+		fields["crosswind"] = fmt.Sprintf("%.0f", b.Crosswind)
+		fields["avg_headwind"] = units.formatSignedWind(b.AvgHeadwind)
+	}
+	return fields
+}
@@ -0,0 +1,75 @@
+package weather
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplateRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"range", "{{range .temp}}x{{end}}"},
+		{"with", "{{with .temp}}{{.}}{{end}}"},
+		{"template", `{{template "stamp"}}`},
+		{"define", `{{define "loop"}}{{template "loop"}}{{end}}{{.temp}}`},
+		{"block", `{{block "inner" .}}{{.temp}}{{end}}`},
+		{"function", `{{printf "%v" .temp}}`},
+		{"pipeline", `{{.temp | printf "%v"}}`},
+		{"variable", "{{$t := .temp}}{{$t}}"},
+		{"dot", "{{.}}"},
+		{"nested field", "{{.temp.value}}"},
+		{"unknown field", "{{.altitude}}"},
+		{"unknown condition", "{{if .altitude}}x{{end}}"},
+		{"function in condition", "{{if not .rain}}dry{{end}}"},
+		{"syntax error", "{{.temp"},
+		{"empty stamp", "{{/* nothing */}}"},
+		{"template too long", strings.Repeat("x", MaxTemplateLength+1)},
+		{"stamp too long", strings.Repeat("{{.temp}}", 50)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseTemplate(test.template); err == nil {
+				t.Errorf("ParseTemplate(%q) succeeded, want an error", test.template)
+			}
+		})
+	}
+}
+
+func TestParseTemplateAccepts(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"empty uses the default", "", "14.1–17.6°C, clouds: 40%, humidity: 72%, wind: 16.6 (29.9 gust) km/h ↗, rain: 0.4 mm\nheadwind: 38%, tailwind: 27%, crosswind: 35% (avg headwind: 4.3 km/h)"},
+		{"fields", "{{.temp_max}}{{.temp_unit}} {{.pressure}} {{.pressure_unit}}", "17.6°C 1016 hPa"},
+		{"condition", "{{if .rain}}wet{{else}}dry{{end}}, {{if .uvi}}uv {{.uvi}}{{end}}", "wet, uv 3.4"},
+		{"trimmed", "  {{- .clouds -}}  %  ", "40%"},
+		{"comment", "{{/* clouds */}}{{.clouds}}%", "40%"},
+		{"longest template", strings.Repeat("x", MaxTemplateLength), strings.Repeat("x", MaxTemplateLength)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stamp, err := PreviewStamp(test.template, Metric)
+			if err != nil || stamp != test.want {
+				t.Errorf("PreviewStamp(%q) = %q, %v, want %q", test.template, stamp, err, test.want)
+			}
+		})
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var w limitedWriter
+	if n, err := w.Write([]byte(strings.Repeat("x", MaxStampLength-1))); err != nil || n != MaxStampLength-1 {
+		t.Fatalf("Write = %v, %v, want %v bytes written", n, err, MaxStampLength-1)
+	}
+	if _, err := w.Write([]byte("ab")); !errors.Is(err, errStampTooLong) {
+		t.Errorf("Write past the limit = %v, want errStampTooLong", err)
+	}
+	if _, err := w.Write([]byte("a")); err != nil || w.Len() != MaxStampLength {
+		t.Errorf("Write up to the limit = %v with %v bytes, want %v", err, w.Len(), MaxStampLength)
+	}
+}
@@ -0,0 +1,161 @@
+package weather
+
+import (
+	"fmt"
+)
+
+// Temperature units.
+const (
+	Celsius    string = "C"
+	Fahrenheit string = "F"
+)
+
+// Wind speed units.
+const (
+	KilometersPerHour string = "km/h"
+	MilesPerHour      string = "mph"
+	MetersPerSecond   string = "m/s"
+	Knots             string = "kn"
+	Beaufort          string = "bft"
+)
+
+// Pressure units.
+const (
+	Hectopascal     string = "hPa"
+	InchesOfMercury string = "inHg"
+)
+
+var TemperatureUnits = []string{Celsius, Fahrenheit}
+var WindUnits = []string{KilometersPerHour, MilesPerHour, MetersPerSecond, Knots, Beaufort}
+var PressureUnits = []string{Hectopascal, InchesOfMercury}
+
+// Units holds the users preferred unit for each kind of measurement.
+// Providers always report in °C, m/s and hPa and stamps are converted locally.
+type Units struct {
+	Temperature string
+	Wind        string
+	Pressure    string
+}
+
+var Metric = Units{Temperature: Celsius, Wind: KilometersPerHour, Pressure: Hectopascal}
+var Imperial = Units{Temperature: Fahrenheit, Wind: MilesPerHour, Pressure: InchesOfMercury}
+
+// UnitsFromSystem maps the legacy "imperial"/"metric" setting to Units.
+func UnitsFromSystem(system string) Units {
+	if system == "metric" {
+		return Metric
+	}
+	return Imperial
+}
+
+// ParseUnits checks each unit against the supported ones.
+func ParseUnits(temperature string, wind string, pressure string) (Units, error) {
+	u := Units{Temperature: temperature, Wind: wind, Pressure: pressure}
+	switch {
+	case !contains(TemperatureUnits, temperature):
+		return u, fmt.Errorf("unsupported temperature unit: %q", temperature)
+	case !contains(WindUnits, wind):
+		return u, fmt.Errorf("unsupported wind speed unit: %q", wind)
+	case !contains(PressureUnits, pressure):
+		return u, fmt.Errorf("unsupported pressure unit: %q", pressure)
+	}
+	return u, nil
+}
+
+// System is the closest legacy "imperial"/"metric" setting.
+func (u Units) System() string {
+	if u.Temperature == Fahrenheit {
+		return "imperial"
+	}
+	return "metric"
+}
+
+// temperature converts from °C.
+func (u Units) temperature(c float32) float32 {
+	if u.Temperature == Fahrenheit {
+		return c*9/5 + 32
+	}
+	return c
+}
+
+// windSpeed converts from m/s.
+func (u Units) windSpeed(ms float32) float32 {
+	switch u.Wind {
+	case KilometersPerHour:
+		return ms * 3.6
+	case MilesPerHour:
+		return ms * 2.236936
+	case Knots:
+		return ms * 1.943844
+	case Beaufort:
+		return float32(beaufort(ms))
+	}
+	return ms
+}
+
+// pressure converts from hPa.
+func (u Units) pressure(hpa float32) float32 {
+	if u.Pressure == InchesOfMercury {
+		return hpa * 0.02953
+	}
+	return hpa
+}
+
+func (u Units) formatWind(ms float32) string {
+	if u.Wind == Beaufort {
+		return fmt.Sprintf("%.0f", u.windSpeed(ms))
+	}
+	return fmt.Sprintf("%0.1f", u.windSpeed(ms))
+}
+
+// formatSignedWind formats a wind component, negative for a tailwind. On the
+// Beaufort scale the force is given the sign of the component.
+func (u Units) formatSignedWind(ms float32) string {
+	if u.Wind == Beaufort {
+		force := beaufort(ms)
+		if ms < 0 {
+			force = -beaufort(-ms)
+		}
+		return fmt.Sprintf("%d", force)
+	}
+	return fmt.Sprintf("%0.1f", u.windSpeed(ms))
+}
+
+func (u Units) formatPressure(hpa float32) string {
+	if u.Pressure == InchesOfMercury {
+		return fmt.Sprintf("%0.2f", u.pressure(hpa))
+	}
+	return fmt.Sprintf("%0.0f", u.pressure(hpa))
+}
+
+func (u Units) tempLabel() string {
+	return "°" + u.Temperature
+}
+
+func (u Units) windLabel() string {
+	if u.Wind == Beaufort {
+		return "Bft"
+	}
+	return u.Wind
+}
+
+// beaufort maps a wind speed in m/s to the Beaufort scale, using the upper
+// bound of each force.
+func beaufort(ms float32) int {
+	limits := [...]float64{0.5, 1.5, 3.3, 5.5, 7.9, 10.7, 13.8, 17.1, 20.7, 24.4, 28.4, 32.6}
+	for force, limit := range limits {
+		if float64(ms) < limit {
+			return force
+		}
+	}
+	return 12
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnits(t *testing.T) {
+	tests := []struct {
+		temperature, wind, pressure string
+		valid                       bool
+	}{
+		{Celsius, KilometersPerHour, Hectopascal, true},
+		{Fahrenheit, Beaufort, InchesOfMercury, true},
+		{"K", KilometersPerHour, Hectopascal, false},
+		{Celsius, "ft/s", Hectopascal, false},
+		{Celsius, KilometersPerHour, "mmHg", false},
+		{"", "", "", false},
+	}
+	for _, test := range tests {
+		units, err := ParseUnits(test.temperature, test.wind, test.pressure)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseUnits(%q, %q, %q) error = %v, want valid %v", test.temperature, test.wind, test.pressure, err, test.valid)
+		}
+		if want := (Units{test.temperature, test.wind, test.pressure}); units != want {
+			t.Errorf("ParseUnits(%q, %q, %q) = %v, want %v", test.temperature, test.wind, test.pressure, units, want)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func(float32) float32
+		in      float32
+		want    float32
+	}{
+		{"°C", Metric.temperature, 20, 20},
+		{"°F", Imperial.temperature, 20, 68},
+		{"°F below zero", Imperial.temperature, -40, -40},
+		{"km/h", Units{Wind: KilometersPerHour}.windSpeed, 10, 36},
+		{"mph", Units{Wind: MilesPerHour}.windSpeed, 10, 22.36936},
+		{"m/s", Units{Wind: MetersPerSecond}.windSpeed, 10, 10},
+		{"kn", Units{Wind: Knots}.windSpeed, 10, 19.43844},
+		{"bft", Units{Wind: Beaufort}.windSpeed, 10, 5},
+		{"hPa", Metric.pressure, 1013, 1013},
+		{"inHg", Imperial.pressure, 1013, 29.91389},
+	}
+	for _, test := range tests {
+		if got := test.convert(test.in); math.Abs(float64(got-test.want)) > 0.001 {
+			t.Errorf("%v: converting %v = %v, want %v", test.name, test.in, got, test.want)
+		}
+	}
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		ms   float32
+		want int
+	}{
+		{0, 0},
+		{0.49, 0},
+		{0.5, 1},
+		{3.2, 2},
+		{3.4, 3},
+		{10.6, 5},
+		{10.8, 6},
+		{32.5, 11},
+		{32.7, 12},
+		{60, 12},
+	}
+	for _, test := range tests {
+		if got := beaufort(test.ms); got != test.want {
+			t.Errorf("beaufort(%v) = %v, want %v", test.ms, got, test.want)
+		}
+	}
+}
+
+func TestFormatWind(t *testing.T) {
+	tests := []struct {
+		wind string
+		ms   float32
+		want string
+	}{
+		{KilometersPerHour, 2.5, "9.0"},
+		{MilesPerHour, 2.5, "5.6"},
+		{MetersPerSecond, 0, "0.0"},
+		{Knots, 10, "19.4"},
+		{Beaufort, 8, "5"},
+	}
+	for _, test := range tests {
+		if got := (Units{Wind: test.wind}).formatWind(test.ms); got != test.want {
+			t.Errorf("formatWind(%v) in %v = %q, want %q", test.ms, test.wind, got, test.want)
+		}
+	}
+}
+
+func TestFormatPressure(t *testing.T) {
+	if got := Metric.formatPressure(1013.4); got != "1013" {
+		t.Errorf("formatPressure in hPa = %q, want 1013", got)
+	}
+	if got := Imperial.formatPressure(1013.4); got != "29.93" {
+		t.Errorf("formatPressure in inHg = %q, want 29.93", got)
+	}
+}
+
+func TestFormatSignedWind(t *testing.T) {
+	tests := []struct {
+		wind string
+		ms   float32
+		want string
+	}{
+		{KilometersPerHour, 2.5, "9.0"},
+		{KilometersPerHour, -2.5, "-9.0"},
+		{MetersPerSecond, 0, "0.0"},
+		{Beaufort, 8, "5"},
+		{Beaufort, -8, "-5"},
+		{Beaufort, -0.2, "0"},
+	}
+	for _, test := range tests {
+		if got := (Units{Wind: test.wind}).formatSignedWind(test.ms); got != test.want {
+			t.Errorf("formatSignedWind(%v) in %v = %q, want %q", test.ms, test.wind, got, test.want)
+		}
+	}
+}
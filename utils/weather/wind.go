@@ -0,0 +1,90 @@
+package weather
+
+import (
+	"math"
+)
+
+const earthRadius float64 = 6371e3
+
+// WindBreakdown is the share of distance ridden into, with, and across the
+// wind, along with the distance weighted average headwind component.
+type WindBreakdown struct {
+	Headwind    float64
+	Tailwind    float64
+	Crosswind   float64
+	AvgHeadwind float32
+}
+
+// Breakdown compares the bearing of each route segment with the wind
+// direction observed closest in time.
+func Breakdown(route Route, observations []Observation) (WindBreakdown, bool) {
+	var b WindBreakdown
+	if len(route) < 2 || len(observations) == 0 {
+		return b, false
+	}
+
+	var total, head, tail, cross, component float64
+	j := 0
+	for i := 1; i < len(route); i++ {
+		a, c := route[i-1], route[i]
+		d := distance(a, c)
+		if d == 0 {
+			continue
+		}
+
+		// Move on to the observation closest to the middle of this segment.
+		mid := (a.Time + c.Time) / 2
+		for j < len(observations)-1 && abs(observations[j+1].Time-mid) <= abs(observations[j].Time-mid) {
+			j++
+		}
+		o := observations[j]
+
+		// Wind_deg is the direction the wind blows from, so riding towards it
+		// gives an angle of zero and a full headwind.
+		angle := math.Mod(bearing(a, c)-o.Wind_deg+540, 360) - 180
+		switch {
+		case math.Abs(angle) <= 45:
+			head += d
+		case math.Abs(angle) >= 135:
+			tail += d
+		default:
+			cross += d
+		}
+		component += d * float64(o.Wind_speed) * math.Cos(angle*math.Pi/180)
+		total += d
+	}
+	if total == 0 {
+		return b, false
+	}
+
+	b.Headwind = 100 * head / total
+	b.Tailwind = 100 * tail / total
+	b.Crosswind = 100 * cross / total
+	b.AvgHeadwind = float32(component / total)
+	return b, true
+}
+
+// bearing is the initial compass bearing from a to b, in degrees.
+func bearing(a Point, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// distance is the haversine distance between a and b, in meters.
+func distance(a Point, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
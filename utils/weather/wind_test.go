@@ -0,0 +1,70 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBreakdown(t *testing.T) {
+	// 2.2 km north, then 1.1 km east, an hour each.
+	north := Route{{Lat: 0, Lng: 0, Time: 0}, {Lat: 0.02, Lng: 0, Time: 3600}}
+	northEast := append(north, Point{Lat: 0.02, Lng: 0.01, Time: 7200})
+	from := func(deg float64) []Observation {
+		return []Observation{{WeatherData: WeatherData{Wind_deg: deg, Wind_speed: 5}}}
+	}
+
+	tests := []struct {
+		name                        string
+		route                       Route
+		observations                []Observation
+		head, tail, cross, headwind float64
+	}{
+		{"headwind", north, from(0), 100, 0, 0, 5},
+		{"headwind at 44°", north, from(44), 100, 0, 0, 5 * math.Cos(44*math.Pi/180)},
+		{"crosswind at 46°", north, from(314), 0, 0, 100, 5 * math.Cos(46*math.Pi/180)},
+		{"crosswind", north, from(90), 0, 0, 100, 0},
+		{"crosswind at 134°", north, from(134), 0, 0, 100, 5 * math.Cos(134*math.Pi/180)},
+		{"tailwind at 136°", north, from(224), 0, 100, 0, 5 * math.Cos(136*math.Pi/180)},
+		{"tailwind", north, from(180), 0, 100, 0, -5},
+		{"weighted by distance", northEast, from(0), 66.7, 0, 33.3, 3.33},
+		{
+			"closest observation",
+			northEast,
+			[]Observation{
+				{WeatherData: WeatherData{Wind_deg: 180, Wind_speed: 5}, Point: Point{Time: 0}},
+				{WeatherData: WeatherData{Wind_deg: 90, Wind_speed: 5}, Point: Point{Time: 7200}},
+			},
+			33.3, 66.7, 0, -3.33 + 1.67,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, ok := Breakdown(test.route, test.observations)
+			near := func(got float64, want float64) bool {
+				return math.Abs(got-want) < 0.1
+			}
+			if !ok || !near(b.Headwind, test.head) || !near(b.Tailwind, test.tail) || !near(b.Crosswind, test.cross) || !near(float64(b.AvgHeadwind), test.headwind) {
+				t.Errorf("Breakdown = %+v, %v, want %v%% head, %v%% tail, %v%% cross, %.2f avg headwind", b, ok, test.head, test.tail, test.cross, test.headwind)
+			}
+		})
+	}
+}
+
+func TestBreakdownWithoutRoute(t *testing.T) {
+	observations := []Observation{{WeatherData: WeatherData{Wind_deg: 0, Wind_speed: 5}}}
+	tests := []struct {
+		name         string
+		route        Route
+		observations []Observation
+	}{
+		{"no points", nil, observations},
+		{"single point", Route{{Lat: 0, Lng: 0, Time: 0}}, observations},
+		{"standing still", Route{{Lat: 0, Lng: 0, Time: 0}, {Lat: 0, Lng: 0, Time: 60}}, observations},
+		{"no observations", Route{{Lat: 0, Lng: 0, Time: 0}, {Lat: 0.01, Lng: 0, Time: 60}}, nil},
+	}
+	for _, test := range tests {
+		if b, ok := Breakdown(test.route, test.observations); ok {
+			t.Errorf("%v: Breakdown = %+v, want none", test.name, b)
+		}
+	}
+}